@@ -0,0 +1,128 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestCrockpot(t *testing.T) {
+	Convey("Given a crockpot without the jardenevent service", t, func() {
+		var received map[string]string
+		fake := &fakeDevice{
+			DeviceType: CrockpotURN,
+			Services:   []string{ServiceBasicEvent},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				switch action {
+				case "GetCrockpotState":
+					return map[string]string{"mode": "51", "time": "240", "cookedTime": "30"}, nil
+				case "SetCrockpotState":
+					received = args
+				}
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		crockpot := &Crockpot{Device: device}
+		ctx := context.Background()
+
+		Convey("When I call #State", func() {
+			state, err := crockpot.State(ctx)
+
+			Convey("Then I expect the basicevent state to be parsed", func() {
+				So(err, ShouldBeNil)
+				So(state.Mode, ShouldEqual, CrockpotLow)
+				So(state.CookTime, ShouldEqual, 4*time.Hour)
+				So(state.CookedTime, ShouldEqual, 30*time.Minute)
+			})
+		})
+
+		Convey("When I call #SetState", func() {
+			err := crockpot.SetState(ctx, CrockpotHigh, 90*time.Minute)
+
+			Convey("Then I expect the mode and minutes to be sent", func() {
+				So(err, ShouldBeNil)
+				So(received["mode"], ShouldEqual, "52")
+				So(received["time"], ShouldEqual, "90")
+			})
+		})
+	})
+}
+
+func TestHumidifier(t *testing.T) {
+	Convey("Given a humidifier", t, func() {
+		var received string
+		fake := &fakeDevice{
+			DeviceType: HumidifierURN,
+			Services:   []string{ServiceBasicEvent, ServiceDeviceEvent},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				switch action {
+				case "GetAttributes":
+					return map[string]string{"attributeList": formatAttributeList(map[string]string{
+						"FanMode":           "3",
+						"DesiredHumidity":   "2",
+						"CurrentHumidity":   "41.5",
+						"NoWater":           "0",
+						"WaterAdvise":       "1",
+						"FilterLife":        "30240",
+						"ExpiredFilterTime": "0",
+					})}, nil
+				case "SetAttributes":
+					received = args["attributeList"]
+				}
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		humidifier := &Humidifier{Device: device}
+		ctx := context.Background()
+
+		Convey("When I call #State", func() {
+			state, err := humidifier.State(ctx)
+
+			Convey("Then I expect the attributes to be translated", func() {
+				So(err, ShouldBeNil)
+				So(state.FanSpeed, ShouldEqual, FanMedium)
+				So(state.DesiredHumidity, ShouldEqual, 55)
+				So(state.CurrentHumidity, ShouldEqual, 41.5)
+				So(state.WaterAdvise, ShouldBeTrue)
+				So(state.FilterLife, ShouldEqual, 50)
+			})
+		})
+
+		Convey("When I call #SetDesiredHumidity", func() {
+			err := humidifier.SetDesiredHumidity(ctx, 60)
+
+			Convey("Then I expect the set point index to be sent", func() {
+				So(err, ShouldBeNil)
+				So(received, ShouldEqual, "<attribute><name>DesiredHumidity</name><value>3</value></attribute>")
+			})
+		})
+
+		Convey("When I call #SetDesiredHumidity with an unsupported value", func() {
+			err := humidifier.SetDesiredHumidity(ctx, 70)
+
+			Convey("Then I expect an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"bytes"
+	"code.google.com/p/go.net/context"
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseAttributeList parses the attribute list used by deviceevent services
+// e.g. <attribute><name>Switch</name><value>0</value></attribute>...  The
+// list arrives HTML-escaped inside the SOAP argument so by the time it gets
+// here it's already been unescaped once
+func parseAttributeList(value string) (map[string]string, error) {
+	list := struct {
+		Attributes []struct {
+			Name  string `xml:"name"`
+			Value string `xml:"value"`
+		} `xml:"attribute"`
+	}{}
+	err := xml.Unmarshal([]byte("<attributeList>"+value+"</attributeList>"), &list)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := map[string]string{}
+	for _, attribute := range list.Attributes {
+		attributes[attribute.Name] = attribute.Value
+	}
+	return attributes, nil
+}
+
+// formatAttributeList is the inverse of parseAttributeList.  The result is
+// escaped a second time when it's encoded into the SOAP envelope, which is
+// what the devices expect
+func formatAttributeList(attributes map[string]string) string {
+	var names []string
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBuffer(nil)
+	for _, name := range names {
+		buf.WriteString("<attribute><name>")
+		xml.EscapeText(buf, []byte(name))
+		buf.WriteString("</name><value>")
+		xml.EscapeText(buf, []byte(attributes[name]))
+		buf.WriteString("</value></attribute>")
+	}
+	return buf.String()
+}
+
+// attributeInt returns the named attribute as an int, or 0 if it's missing
+func attributeInt(attributes map[string]string, name string) int {
+	value, _ := strconv.Atoi(strings.TrimSpace(attributes[name]))
+	return value
+}
+
+// attributeFloat returns the named attribute as a float64, or 0 if it's missing
+func attributeFloat(attributes map[string]string, name string) float64 {
+	value, _ := strconv.ParseFloat(strings.TrimSpace(attributes[name]), 64)
+	return value
+}
+
+// GetAttributes returns the attributes of devices using the deviceevent
+// service e.g. Maker and the Jarden appliances
+func (d *Device) GetAttributes(ctx context.Context) (map[string]string, error) {
+	response, err := d.Call(ctx, ServiceDeviceEvent, "GetAttributes", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAttributeList(response.Args["attributeList"])
+}
+
+// SetAttributes changes one or more attributes on the deviceevent service
+func (d *Device) SetAttributes(ctx context.Context, attributes map[string]string) error {
+	args := map[string]string{"attributeList": formatAttributeList(attributes)}
+	_, err := d.Call(ctx, ServiceDeviceEvent, "SetAttributes", args)
+	return err
+}
+
+// Attributes returns the attributes carried by an attributeList event.  Events
+// typically only include the attributes that changed
+func (e Event) Attributes() (map[string]string, bool) {
+	if e.Name != "attributeList" {
+		return nil, false
+	}
+
+	attributes, err := parseAttributeList(e.Value)
+	if err != nil {
+		return nil, false
+	}
+	return attributes, true
+}
@@ -0,0 +1,124 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type ResetMode int
+
+const (
+	// ResetData clears the name, icon, rules and other personal info
+	ResetData ResetMode = 1
+
+	// ResetAll restores the factory defaults
+	ResetAll ResetMode = 2
+
+	// ResetWiFi forgets the home network and restarts the setup access point
+	ResetWiFi ResetMode = 5
+)
+
+type MacAddr struct {
+	MacAddr   string
+	SerialNo  string
+	PluginUDN string
+}
+
+func (d *Device) basicEvent(ctx context.Context, action, key string) (string, error) {
+	response, err := d.Call(ctx, ServiceBasicEvent, action, nil)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := response.Args[key]
+	if !ok {
+		return "", fmt.Errorf("%s response did not contain %s", action, key)
+	}
+
+	return value, nil
+}
+
+func (d *Device) GetFriendlyName(ctx context.Context) (string, error) {
+	value, err := d.basicEvent(ctx, "GetFriendlyName", "FriendlyName")
+	if err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+func (d *Device) ChangeFriendlyName(ctx context.Context, name string) error {
+	if name == "" {
+		return fmt.Errorf("friendly name must not be empty")
+	}
+
+	_, err := d.Call(ctx, ServiceBasicEvent, "ChangeFriendlyName", map[string]string{"FriendlyName": name})
+	return err
+}
+
+// GetSignalStrength returns the wifi signal strength in percent
+func (d *Device) GetSignalStrength(ctx context.Context) (int, error) {
+	value, err := d.basicEvent(ctx, "GetSignalStrength", "SignalStrength")
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(value))
+}
+
+func (d *Device) GetMacAddr(ctx context.Context) (*MacAddr, error) {
+	response, err := d.Call(ctx, ServiceBasicEvent, "GetMacAddr", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MacAddr{
+		MacAddr:   response.Args["MacAddr"],
+		SerialNo:  response.Args["SerialNo"],
+		PluginUDN: response.Args["PluginUDN"],
+	}, nil
+}
+
+func (d *Device) GetIconURL(ctx context.Context) (string, error) {
+	return d.basicEvent(ctx, "GetIconURL", "URL")
+}
+
+func (d *Device) GetLogFileURL(ctx context.Context) (string, error) {
+	return d.basicEvent(ctx, "GetLogFileURL", "LOGURL")
+}
+
+// ReSetup resets the device.  ResetWiFi and ResetAll leave the device
+// unreachable until it's provisioned again; see Provisioner
+func (d *Device) ReSetup(ctx context.Context, mode ResetMode) error {
+	switch mode {
+	case ResetData, ResetAll, ResetWiFi:
+	default:
+		return fmt.Errorf("unknown reset mode, %d", mode)
+	}
+
+	response, err := d.Call(ctx, ServiceBasicEvent, "ReSetup", map[string]string{"Reset": strconv.Itoa(int(mode))})
+	if err != nil {
+		return err
+	}
+
+	if result := response.Args["Reset"]; result != "" && !strings.HasPrefix(result, "success") && result != "reset_remote" {
+		return fmt.Errorf("ReSetup failed => %s", result)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestBasicEvent(t *testing.T) {
+	Convey("Given a device", t, func() {
+		name := "Lamp"
+		var reset string
+		fake := &fakeDevice{
+			DeviceType: "urn:Belkin:device:controllee:1",
+			Services:   []string{ServiceBasicEvent},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				switch action {
+				case "ChangeFriendlyName":
+					name = args["FriendlyName"]
+				case "GetFriendlyName":
+					return map[string]string{"FriendlyName": name}, nil
+				case "GetSignalStrength":
+					return map[string]string{"SignalStrength": "87"}, nil
+				case "GetMacAddr":
+					return map[string]string{"MacAddr": "EC1A59F1C2D4", "SerialNo": "221326K0101234", "PluginUDN": "uuid:Socket-1_0-221326K0101234"}, nil
+				case "ReSetup":
+					reset = args["Reset"]
+					return map[string]string{"Reset": "success"}, nil
+				}
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		ctx := context.Background()
+
+		Convey("When I rename it with characters that need escaping", func() {
+			err := device.ChangeFriendlyName(ctx, "Tom & Jerry's <Lamp>")
+			So(err, ShouldBeNil)
+
+			Convey("Then I expect the name to round trip", func() {
+				value, err := device.GetFriendlyName(ctx)
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, "Tom & Jerry's <Lamp>")
+			})
+		})
+
+		Convey("When I rename it with something that looks like an entity", func() {
+			err := device.ChangeFriendlyName(ctx, "Tom &amp; Jerry")
+			So(err, ShouldBeNil)
+
+			Convey("Then I expect the name to round trip unchanged", func() {
+				value, err := device.GetFriendlyName(ctx)
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, "Tom &amp; Jerry")
+			})
+		})
+
+		Convey("When I call #GetSignalStrength", func() {
+			value, err := device.GetSignalStrength(ctx)
+
+			Convey("Then I expect the percentage", func() {
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, 87)
+			})
+		})
+
+		Convey("When I call #GetMacAddr", func() {
+			value, err := device.GetMacAddr(ctx)
+
+			Convey("Then I expect the mac, serial and udn", func() {
+				So(err, ShouldBeNil)
+				So(value.MacAddr, ShouldEqual, "EC1A59F1C2D4")
+				So(value.SerialNo, ShouldEqual, "221326K0101234")
+				So(value.PluginUDN, ShouldEqual, "uuid:Socket-1_0-221326K0101234")
+			})
+		})
+
+		Convey("When I call #ReSetup", func() {
+			err := device.ReSetup(ctx, ResetWiFi)
+
+			Convey("Then I expect the reset mode to be sent", func() {
+				So(err, ShouldBeNil)
+				So(reset, ShouldEqual, "5")
+			})
+		})
+
+		Convey("When I call #ReSetup with an unknown mode", func() {
+			err := device.ReSetup(ctx, ResetMode(3))
+
+			Convey("Then I expect an error", func() {
+				So(err, ShouldNotBeNil)
+				So(reset, ShouldEqual, "")
+			})
+		})
+	})
+}
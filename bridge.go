@@ -0,0 +1,330 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BridgeURN     = "urn:Belkin:device:bridge:1"
+	ServiceBridge = "urn:Belkin:service:bridge:1"
+)
+
+// capabilities reported by bulbs paired with a bridge
+const (
+	CapabilityOnOff            = "10006"
+	CapabilityLevel            = "10008"
+	CapabilityColor            = "10300"
+	CapabilityColorTemperature = "30301"
+)
+
+// Bridge is the WeMo Link; it controls the LED bulbs paired with it
+type Bridge struct {
+	*Device
+}
+
+// EndDevice is a bulb, or a group of bulbs, paired with a bridge
+type EndDevice struct {
+	Bridge          *Bridge           `json:"-"`
+	ID              string            `json:"id"`
+	FriendlyName    string            `json:"friendly-name"`
+	FirmwareVersion string            `json:"firmware-version,omitempty"`
+	Manufacturer    string            `json:"manufacturer,omitempty"`
+	ModelCode       string            `json:"model-code,omitempty"`
+	IsGroup         bool              `json:"group"`
+	Members         []*EndDevice      `json:"members,omitempty"`
+	Capabilities    map[string]string `json:"capabilities"`
+}
+
+type endDeviceInfo struct {
+	DeviceID        string `xml:"DeviceID"`
+	FriendlyName    string `xml:"FriendlyName"`
+	FirmwareVersion string `xml:"FirmwareVersion"`
+	CapabilityIDs   string `xml:"CapabilityIDs"`
+	CurrentState    string `xml:"CurrentState"`
+	Manufacturer    string `xml:"Manufacturer"`
+	ModelCode       string `xml:"ModelCode"`
+}
+
+type endDeviceLists struct {
+	DeviceLists []struct {
+		DeviceInfos []endDeviceInfo `xml:"DeviceInfos>DeviceInfo"`
+		GroupInfos  []struct {
+			GroupID               string          `xml:"GroupID"`
+			GroupName             string          `xml:"GroupName"`
+			GroupCapabilityIDs    string          `xml:"GroupCapabilityIDs"`
+			GroupCapabilityValues string          `xml:"GroupCapabilityValues"`
+			DeviceInfos           []endDeviceInfo `xml:"DeviceInfos>DeviceInfo"`
+		} `xml:"GroupInfos>GroupInfo"`
+	} `xml:"DeviceList"`
+}
+
+// zipCapabilities pairs a comma separated list of capability ids with the
+// matching comma separated list of values
+func zipCapabilities(ids, values string) map[string]string {
+	capabilities := map[string]string{}
+	if ids == "" {
+		return capabilities
+	}
+
+	v := strings.Split(values, ",")
+	for i, id := range strings.Split(ids, ",") {
+		value := ""
+		if i < len(v) {
+			value = v[i]
+		}
+		capabilities[strings.TrimSpace(id)] = value
+	}
+	return capabilities
+}
+
+func (b *Bridge) newEndDevice(info endDeviceInfo) *EndDevice {
+	return &EndDevice{
+		Bridge:          b,
+		ID:              info.DeviceID,
+		FriendlyName:    info.FriendlyName,
+		FirmwareVersion: info.FirmwareVersion,
+		Manufacturer:    info.Manufacturer,
+		ModelCode:       info.ModelCode,
+		Capabilities:    zipCapabilities(info.CapabilityIDs, info.CurrentState),
+	}
+}
+
+func (b *Bridge) unmarshalEndDevices(data string) ([]*EndDevice, error) {
+	lists := endDeviceLists{}
+	err := xml.Unmarshal([]byte(data), &lists)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []*EndDevice
+	for _, list := range lists.DeviceLists {
+		for _, info := range list.DeviceInfos {
+			devices = append(devices, b.newEndDevice(info))
+		}
+
+		for _, group := range list.GroupInfos {
+			device := &EndDevice{
+				Bridge:       b,
+				ID:           group.GroupID,
+				FriendlyName: group.GroupName,
+				IsGroup:      true,
+				Capabilities: zipCapabilities(group.GroupCapabilityIDs, group.GroupCapabilityValues),
+			}
+			for _, info := range group.DeviceInfos {
+				device.Members = append(device.Members, b.newEndDevice(info))
+			}
+			devices = append(devices, device)
+		}
+	}
+
+	return devices, nil
+}
+
+// EndDevices lists the bulbs and groups paired with the bridge
+func (b *Bridge) EndDevices(ctx context.Context) ([]*EndDevice, error) {
+	deviceInfo, err := b.FetchDeviceInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := b.Call(ctx, ServiceBridge, "GetEndDevices", map[string]string{
+		"DevUDN":      deviceInfo.UDN,
+		"ReqListType": "PAIRED_LIST",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b.unmarshalEndDevices(response.Args["DeviceLists"])
+}
+
+// DeviceStatus returns the current capability values for each of the ids
+func (b *Bridge) DeviceStatus(ctx context.Context, ids ...string) (map[string]map[string]string, error) {
+	response, err := b.Call(ctx, ServiceBridge, "GetDeviceStatus", map[string]string{
+		"DeviceIDs": strings.Join(ids, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := struct {
+		Statuses []struct {
+			DeviceID        string `xml:"DeviceID"`
+			CapabilityID    string `xml:"CapabilityID"`
+			CapabilityValue string `xml:"CapabilityValue"`
+		} `xml:"DeviceStatus"`
+	}{}
+	err = xml.Unmarshal([]byte(response.Args["DeviceStatusList"]), &list)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := map[string]map[string]string{}
+	for _, status := range list.Statuses {
+		statuses[status.DeviceID] = zipCapabilities(status.CapabilityID, status.CapabilityValue)
+	}
+	return statuses, nil
+}
+
+func newDeviceStatus(id string, isGroup bool, capabilities map[string]string) (string, error) {
+	var ids, values []string
+	for capability := range capabilities {
+		ids = append(ids, capability)
+	}
+	sort.Strings(ids)
+	for _, capability := range ids {
+		values = append(values, capabilities[capability])
+	}
+
+	status := struct {
+		XMLName       xml.Name `xml:"DeviceStatus"`
+		IsGroupAction string   `xml:"IsGroupAction"`
+		DeviceID      struct {
+			Available string `xml:"available,attr"`
+			ID        string `xml:",chardata"`
+		} `xml:"DeviceID"`
+		CapabilityID    string `xml:"CapabilityID"`
+		CapabilityValue string `xml:"CapabilityValue"`
+	}{
+		IsGroupAction:   "NO",
+		CapabilityID:    strings.Join(ids, ","),
+		CapabilityValue: strings.Join(values, ","),
+	}
+	if isGroup {
+		status.IsGroupAction = "YES"
+	}
+	status.DeviceID.Available = "YES"
+	status.DeviceID.ID = id
+
+	data, err := xml.Marshal(status)
+	if err != nil {
+		return "", err
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>` + string(data), nil
+}
+
+// SetDeviceStatus sets capability values, keyed by capability id, on a bulb or group
+func (b *Bridge) SetDeviceStatus(ctx context.Context, id string, isGroup bool, capabilities map[string]string) error {
+	status, err := newDeviceStatus(id, isGroup, capabilities)
+	if err != nil {
+		return err
+	}
+
+	response, err := b.Call(ctx, ServiceBridge, "SetDeviceStatus", map[string]string{
+		"DeviceStatusList": status,
+	})
+	if err != nil {
+		return err
+	}
+
+	if failed := strings.TrimSpace(response.Args["ErrorDeviceIDs"]); failed != "" {
+		return fmt.Errorf("SetDeviceStatus failed for devices => %s", failed)
+	}
+	return nil
+}
+
+// transition times are expressed in tenths of a second
+func tenths(transition time.Duration) int {
+	return int(transition / (100 * time.Millisecond))
+}
+
+func (e *EndDevice) set(ctx context.Context, capability, value string) error {
+	err := e.Bridge.SetDeviceStatus(ctx, e.ID, e.IsGroup, map[string]string{capability: value})
+	if err != nil {
+		return err
+	}
+
+	e.Capabilities[capability] = value
+	return nil
+}
+
+func (e *EndDevice) On(ctx context.Context) error {
+	return e.set(ctx, CapabilityOnOff, "1")
+}
+
+func (e *EndDevice) Off(ctx context.Context) error {
+	return e.set(ctx, CapabilityOnOff, "0")
+}
+
+// IsOn reports the on/off state as of the last refresh
+func (e *EndDevice) IsOn() bool {
+	return e.Capabilities[CapabilityOnOff] == "1"
+}
+
+// SetBrightness fades the bulb to percent (0-100) over the transition time
+func (e *EndDevice) SetBrightness(ctx context.Context, percent int, transition time.Duration) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("brightness must be between 0 and 100 => %d", percent)
+	}
+
+	level := (percent*255 + 50) / 100
+	return e.set(ctx, CapabilityLevel, fmt.Sprintf("%d:%d", level, tenths(transition)))
+}
+
+// Brightness returns the brightness, as a percentage, as of the last refresh
+func (e *EndDevice) Brightness() (int, bool) {
+	value, ok := e.Capabilities[CapabilityLevel]
+	if !ok {
+		return 0, false
+	}
+
+	level, err := strconv.Atoi(strings.SplitN(value, ":", 2)[0])
+	if err != nil {
+		return 0, false
+	}
+	return (level*100 + 127) / 255, true
+}
+
+// SetColorTemperature changes the white temperature, in kelvin, of tunable bulbs
+func (e *EndDevice) SetColorTemperature(ctx context.Context, kelvin int, transition time.Duration) error {
+	if kelvin <= 0 {
+		return fmt.Errorf("invalid color temperature => %d", kelvin)
+	}
+
+	mireds := 1000000 / kelvin
+	return e.set(ctx, CapabilityColorTemperature, fmt.Sprintf("%d:%d", mireds, tenths(transition)))
+}
+
+// SetColor changes the color of color bulbs to the CIE 1931 x, y coordinate
+func (e *EndDevice) SetColor(ctx context.Context, x, y float64, transition time.Duration) error {
+	if x < 0 || x > 1 || y < 0 || y > 1 {
+		return fmt.Errorf("invalid color coordinate => %v, %v", x, y)
+	}
+
+	value := fmt.Sprintf("%d:%d:%d", int(x*65535), int(y*65535), tenths(transition))
+	return e.set(ctx, CapabilityColor, value)
+}
+
+// Refresh reloads the capability values from the bridge
+func (e *EndDevice) Refresh(ctx context.Context) error {
+	statuses, err := e.Bridge.DeviceStatus(ctx, e.ID)
+	if err != nil {
+		return err
+	}
+
+	capabilities, ok := statuses[e.ID]
+	if !ok {
+		return fmt.Errorf("bridge returned no status for %s", e.ID)
+	}
+	e.Capabilities = capabilities
+	return nil
+}
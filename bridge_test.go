@@ -0,0 +1,92 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+const testDeviceLists = `<?xml version="1.0" encoding="utf-8"?><DeviceLists><DeviceList><DeviceListType>Paired</DeviceListType><DeviceInfos><DeviceInfo><DeviceIndex>0</DeviceIndex><DeviceID>94103EA2B27751AF</DeviceID><FriendlyName>Hall Bulb</FriendlyName><IconVersion>1</IconVersion><FirmwareVersion>7E</FirmwareVersion><CapabilityIDs>10006,10008,30008,30009,3000A</CapabilityIDs><CurrentState>1,255:0,,,</CurrentState><Manufacturer>MRVL</Manufacturer><ModelCode>MZ100</ModelCode><productName>Lighting</productName><WeMoCertified>YES</WeMoCertified></DeviceInfo></DeviceInfos><GroupInfos><GroupInfo><GroupID>1415739380</GroupID><GroupName>Kitchen</GroupName><GroupCapabilityIDs>10006,10008</GroupCapabilityIDs><GroupCapabilityValues>0,128:0</GroupCapabilityValues><DeviceInfos><DeviceInfo><DeviceID>94103EA2B2775AAA</DeviceID><FriendlyName>Kitchen 1</FriendlyName><CapabilityIDs>10006,10008</CapabilityIDs><CurrentState>0,128:0</CurrentState></DeviceInfo></DeviceInfos></GroupInfo></GroupInfos></DeviceList></DeviceLists>`
+
+func TestBridge(t *testing.T) {
+	Convey("Given a bridge with a bulb and a group", t, func() {
+		var status string
+		fake := &fakeDevice{
+			DeviceType: BridgeURN,
+			Services:   []string{ServiceBasicEvent, ServiceBridge},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				switch action {
+				case "GetEndDevices":
+					return map[string]string{"DeviceLists": testDeviceLists}, nil
+				case "SetDeviceStatus":
+					status = args["DeviceStatusList"]
+					return map[string]string{"ErrorDeviceIDs": ""}, nil
+				}
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		bridge := &Bridge{Device: device}
+		ctx := context.Background()
+
+		Convey("When I call #EndDevices", func() {
+			devices, err := bridge.EndDevices(ctx)
+			So(err, ShouldBeNil)
+			So(len(devices), ShouldEqual, 2)
+
+			Convey("Then I expect the bulb to be parsed", func() {
+				bulb := devices[0]
+				So(bulb.ID, ShouldEqual, "94103EA2B27751AF")
+				So(bulb.FriendlyName, ShouldEqual, "Hall Bulb")
+				So(bulb.IsOn(), ShouldBeTrue)
+
+				brightness, ok := bulb.Brightness()
+				So(ok, ShouldBeTrue)
+				So(brightness, ShouldEqual, 100)
+			})
+
+			Convey("Then I expect the group to be parsed", func() {
+				group := devices[1]
+				So(group.IsGroup, ShouldBeTrue)
+				So(group.FriendlyName, ShouldEqual, "Kitchen")
+				So(len(group.Members), ShouldEqual, 1)
+				So(group.IsOn(), ShouldBeFalse)
+			})
+
+			Convey("And I dim the bulb", func() {
+				err := devices[0].SetBrightness(ctx, 50, 2*time.Second)
+
+				Convey("Then I expect the level and transition to be sent", func() {
+					So(err, ShouldBeNil)
+					So(status, ShouldEqual, `<?xml version="1.0" encoding="UTF-8"?><DeviceStatus><IsGroupAction>NO</IsGroupAction><DeviceID available="YES">94103EA2B27751AF</DeviceID><CapabilityID>10008</CapabilityID><CapabilityValue>128:20</CapabilityValue></DeviceStatus>`)
+				})
+			})
+
+			Convey("And I turn the group on", func() {
+				err := devices[1].On(ctx)
+
+				Convey("Then I expect a group action", func() {
+					So(err, ShouldBeNil)
+					So(status, ShouldContainSubstring, "<IsGroupAction>YES</IsGroupAction>")
+					So(devices[1].IsOn(), ShouldBeTrue)
+				})
+			})
+		})
+	})
+}
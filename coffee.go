@@ -0,0 +1,75 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"strconv"
+)
+
+const CoffeeMakerURN = "urn:Belkin:device:CoffeeMaker:1"
+
+type CoffeeMakerMode int
+
+const (
+	CoffeeRefill                CoffeeMakerMode = 0
+	CoffeePlaceCarafe           CoffeeMakerMode = 1
+	CoffeeRefillWater           CoffeeMakerMode = 2
+	CoffeeReady                 CoffeeMakerMode = 3
+	CoffeeBrewing               CoffeeMakerMode = 4
+	CoffeeBrewed                CoffeeMakerMode = 5
+	CoffeeCleaningBrewing       CoffeeMakerMode = 6
+	CoffeeCleaningSoaking       CoffeeMakerMode = 7
+	CoffeeBrewFailCarafeRemoved CoffeeMakerMode = 8
+)
+
+var coffeeMakerModes = map[CoffeeMakerMode]string{
+	CoffeeRefill:                "refill",
+	CoffeePlaceCarafe:           "place carafe",
+	CoffeeRefillWater:           "refill water",
+	CoffeeReady:                 "ready",
+	CoffeeBrewing:               "brewing",
+	CoffeeBrewed:                "brewed",
+	CoffeeCleaningBrewing:       "cleaning brewing",
+	CoffeeCleaningSoaking:       "cleaning soaking",
+	CoffeeBrewFailCarafeRemoved: "brew failed, carafe removed",
+}
+
+func (m CoffeeMakerMode) String() string {
+	if name, ok := coffeeMakerModes[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("CoffeeMakerMode(%d)", int(m))
+}
+
+// CoffeeMaker is the WeMo enabled Mr. Coffee
+type CoffeeMaker struct {
+	*Device
+}
+
+func (c *CoffeeMaker) Mode(ctx context.Context) (CoffeeMakerMode, error) {
+	attributes, err := c.GetAttributes(ctx)
+	if err != nil {
+		return CoffeeRefill, err
+	}
+	return CoffeeMakerMode(attributeInt(attributes, "Mode")), nil
+}
+
+// Brew starts brewing; the coffee maker has to be in the ready mode
+func (c *CoffeeMaker) Brew(ctx context.Context) error {
+	return c.SetAttributes(ctx, map[string]string{
+		"Mode": strconv.Itoa(int(CoffeeBrewing)),
+	})
+}
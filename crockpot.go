@@ -0,0 +1,114 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	CrockpotURN        = "urn:Belkin:device:crockpot:1"
+	ServiceJardenEvent = "urn:Belkin:service:jardenevent:1"
+)
+
+type CrockpotMode int
+
+const (
+	CrockpotOff  CrockpotMode = 0
+	CrockpotWarm CrockpotMode = 50
+	CrockpotLow  CrockpotMode = 51
+	CrockpotHigh CrockpotMode = 52
+)
+
+func (m CrockpotMode) String() string {
+	switch m {
+	case CrockpotOff:
+		return "off"
+	case CrockpotWarm:
+		return "warm"
+	case CrockpotLow:
+		return "low"
+	case CrockpotHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("CrockpotMode(%d)", int(m))
+	}
+}
+
+type CrockpotState struct {
+	Mode CrockpotMode `json:"mode"`
+	// CookTime is the time left on the timer
+	CookTime time.Duration `json:"cook-time"`
+	// CookedTime is how long the crockpot has been cooking in the current mode
+	CookedTime time.Duration `json:"cooked-time"`
+}
+
+type Crockpot struct {
+	*Device
+}
+
+// serviceType returns the jardenevent service when the firmware advertises
+// it; older firmware only offers the crockpot actions on basicevent
+func (c *Crockpot) serviceType(ctx context.Context) (string, error) {
+	if _, err := c.service(ctx, ServiceJardenEvent); err == nil {
+		return ServiceJardenEvent, nil
+	}
+	if _, err := c.service(ctx, ServiceBasicEvent); err != nil {
+		return "", err
+	}
+	return ServiceBasicEvent, nil
+}
+
+func (c *Crockpot) State(ctx context.Context) (*CrockpotState, error) {
+	serviceType, err := c.serviceType(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.Call(ctx, serviceType, "GetCrockpotState", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CrockpotState{
+		Mode:       CrockpotMode(attributeInt(response.Args, "mode")),
+		CookTime:   time.Duration(attributeInt(response.Args, "time")) * time.Minute,
+		CookedTime: time.Duration(attributeInt(response.Args, "cookedTime")) * time.Minute,
+	}, nil
+}
+
+// SetState switches the crockpot to mode and, for low and high, cooks for
+// cookTime before dropping to warm.  The device works in whole minutes
+func (c *Crockpot) SetState(ctx context.Context, mode CrockpotMode, cookTime time.Duration) error {
+	switch mode {
+	case CrockpotOff, CrockpotWarm, CrockpotLow, CrockpotHigh:
+	default:
+		return fmt.Errorf("unknown crockpot mode => %d", int(mode))
+	}
+
+	serviceType, err := c.serviceType(ctx)
+	if err != nil {
+		return err
+	}
+
+	args := map[string]string{
+		"mode": strconv.Itoa(int(mode)),
+		"time": strconv.Itoa(int(cookTime / time.Minute)),
+	}
+	_, err = c.Call(ctx, serviceType, "SetCrockpotState", args)
+	return err
+}
@@ -16,28 +16,67 @@ package wemo
 import (
 	"code.google.com/p/go.net/context"
 	"encoding/xml"
-	"errors"
 	"fmt"
 	"github.com/savaki/httpctx"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"regexp"
-	"strconv"
+	"sync"
 )
 
 type Device struct {
 	Host   string
 	Logger func(string, ...interface{}) (int, error)
+
+	// UDN, if set, identifies the device so it can be found again after it
+	// moves to another port.  Otherwise it's learned from setup.xml
+	UDN string
+
+	// OnHostChange, if set, is called when the device is found on a new port
+	OnHostChange func(oldHost, newHost string)
+
+	mutex    sync.Mutex
+	services []Service
+	udn      string
+
+	// current is the host the device moved to; Host is left as given
+	current string
+
+	// relocating serializes searches for a device that moved
+	relocating sync.Mutex
+}
+
+type Icon struct {
+	MimeType string `xml:"mimetype" json:"mimetype"`
+	Width    int    `xml:"width" json:"width"`
+	Height   int    `xml:"height" json:"height"`
+	Depth    int    `xml:"depth" json:"depth"`
+	URL      string `xml:"url" json:"url"`
 }
 
 type DeviceInfo struct {
-	Device          *Device `json:"-"`
-	DeviceType      string  `xml:"deviceType" json:"device-type"`
-	FriendlyName    string  `xml:"friendlyName" json:"friendly-name"`
-	MacAddress      string  `xml:"macAddress" json:"mac-address"`
-	FirmwareVersion string  `xml:"firmwareVersion" json:"firmware-version"`
-	SerialNumber    string  `xml:"serialNumber" json:"serial-number"`
+	Device           *Device   `json:"-"`
+	DeviceType       string    `xml:"deviceType" json:"device-type"`
+	FriendlyName     string    `xml:"friendlyName" json:"friendly-name"`
+	Manufacturer     string    `xml:"manufacturer" json:"manufacturer"`
+	ManufacturerURL  string    `xml:"manufacturerURL" json:"manufacturer-url"`
+	ModelDescription string    `xml:"modelDescription" json:"model-description"`
+	ModelName        string    `xml:"modelName" json:"model-name"`
+	ModelNumber      string    `xml:"modelNumber" json:"model-number"`
+	ModelURL         string    `xml:"modelURL" json:"model-url"`
+	UDN              string    `xml:"UDN" json:"udn"`
+	UPC              string    `xml:"UPC" json:"upc"`
+	MacAddress       string    `xml:"macAddress" json:"mac-address"`
+	FirmwareVersion  string    `xml:"firmwareVersion" json:"firmware-version"`
+	SerialNumber     string    `xml:"serialNumber" json:"serial-number"`
+	IconVersion      string    `xml:"iconVersion" json:"icon-version"`
+	BinaryState      string    `xml:"binaryState" json:"binary-state"`
+	PresentationURL  string    `xml:"presentationURL" json:"presentation-url"`
+	Icons            []Icon    `xml:"iconList>icon" json:"icons,omitempty"`
+	Services         []Service `xml:"serviceList>service" json:"services,omitempty"`
+}
+
+// Service returns the service with the given serviceType, if the device offers it
+func (d *DeviceInfo) Service(serviceType string) (Service, bool) {
+	return findService(d.Services, serviceType)
 }
 
 type DeviceInfos []*DeviceInfo
@@ -64,52 +103,51 @@ func unmarshalDeviceInfo(data []byte) (*DeviceInfo, error) {
 	return &resp.DeviceInfo, nil
 }
 
-func (d *Device) FetchDeviceInfo(ctx context.Context) (*DeviceInfo, error) {
+func fetchDeviceInfo(ctx context.Context, host string) (*DeviceInfo, error) {
 	var data []byte
 
-	uri := fmt.Sprintf("http://%s/setup.xml", d.Host)
+	uri := fmt.Sprintf("http://%s/setup.xml", host)
 	err := httpctx.NewClient().Get(ctx, uri, nil, &data)
 	if err != nil {
 		return nil, err
 	}
 
-	deviceInfo, err := unmarshalDeviceInfo(data)
+	return unmarshalDeviceInfo(data)
+}
+
+func (d *Device) FetchDeviceInfo(ctx context.Context) (*DeviceInfo, error) {
+	host := d.CurrentHost()
+	deviceInfo, err := fetchDeviceInfo(ctx, host)
+	if isConnectionRefused(err) {
+		return d.relocate(ctx, host, err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	d.mutex.Lock()
+	d.services = deviceInfo.Services
+	d.udn = deviceInfo.UDN
+	d.mutex.Unlock()
+
 	deviceInfo.Device = d
 	return deviceInfo, nil
 }
 
 func (d *Device) GetBinaryState() int {
-	message := newGetBinaryStateMessage()
-	response, err := post(d.Host, "GetBinaryState", message)
+	response, err := d.Call(context.Background(), ServiceBasicEvent, "GetBinaryState", nil)
 	if err != nil {
 		d.printf("unable to fetch BinaryState => %s\n", err)
 		return -1
 	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		d.printf("GetBinaryState returned status code => %d\n", response.StatusCode)
-		return -1
-	}
-
-	data, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		d.printf("unable to read data => %s\n", err)
-		return -1
-	}
 
-	re := regexp.MustCompile(`.*<BinaryState>(\d+)</BinaryState>.*`)
-	matches := re.FindStringSubmatch(string(data))
-	if len(matches) != 2 {
-		d.printf("unable to find BinaryState response in message => %s\n", string(data))
+	value, ok := response.Args["BinaryState"]
+	if !ok {
+		d.printf("unable to find BinaryState response in message => %s\n", string(response.data))
 		return -1
 	}
 
-	result, _ := strconv.Atoi(matches[1])
+	result, _ := parseBinaryState(value)
 	return result
 }
 
@@ -131,26 +169,16 @@ func (d *Device) Toggle() {
 
 func (d *Device) changeState(newState bool) error {
 	fmt.Printf("changeState(%v)\n", newState)
-	message := newSetBinaryStateMessage(newState)
-	response, err := post(d.Host, "SetBinaryState", message)
+	value := "0"
+	if newState {
+		value = "1"
+	}
+
+	_, err := d.Call(context.Background(), ServiceBasicEvent, "SetBinaryState", map[string]string{"BinaryState": value})
 	if err != nil {
-		log.Println("unable to SetBinaryState")
+		log.Printf("changeState(%v) => %s\n", newState, err)
 		return err
 	}
-	defer response.Body.Close()
-
-	if response.StatusCode != 200 {
-		data, err := ioutil.ReadAll(response.Body)
-		if err != nil {
-			log.Println("couldn't read body from message => " + err.Error())
-			return err
-		}
-		content := string(data)
-
-		gripe := fmt.Sprintf("changeState(%v) => %s", newState, content)
-		log.Println(gripe)
-		return errors.New(gripe)
-	}
 
 	return nil
 }
@@ -148,6 +148,37 @@ func TestParseResponseXML(t *testing.T) {
 			Convey("Then I expect SerialNumber to be set", func() {
 				So(deviceInfo.SerialNumber, ShouldEqual, "221248K0102C92")
 			})
+
+			Convey("Then I expect UDN to be set", func() {
+				So(deviceInfo.UDN, ShouldEqual, "uuid:Socket-1_0-221248K0102C92")
+			})
+
+			Convey("Then I expect the model to be set", func() {
+				So(deviceInfo.Manufacturer, ShouldEqual, "Belkin International Inc.")
+				So(deviceInfo.ModelName, ShouldEqual, "Socket")
+				So(deviceInfo.ModelNumber, ShouldEqual, "1.0")
+				So(deviceInfo.ModelDescription, ShouldEqual, "Belkin Plugin Socket 1.0")
+			})
+
+			Convey("Then I expect BinaryState to be set", func() {
+				So(deviceInfo.BinaryState, ShouldEqual, "1")
+			})
+
+			Convey("Then I expect the icons to be set", func() {
+				So(len(deviceInfo.Icons), ShouldEqual, 1)
+				So(deviceInfo.Icons[0].URL, ShouldEqual, "icon.jpg")
+				So(deviceInfo.Icons[0].Width, ShouldEqual, 100)
+			})
+
+			Convey("Then I expect all the services to be set", func() {
+				So(len(deviceInfo.Services), ShouldEqual, 8)
+
+				service, ok := deviceInfo.Service(ServiceRules)
+				So(ok, ShouldBeTrue)
+				So(service.ControlURL, ShouldEqual, "/upnp/control/rules1")
+				So(service.EventSubURL, ShouldEqual, "/upnp/event/rules1")
+				So(service.SCPDURL, ShouldEqual, "/rulesservice.xml")
+			})
 		})
 	})
 }
@@ -0,0 +1,104 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	SwitchURN      = "urn:Belkin:device:controllee:1"
+	LightSwitchURN = "urn:Belkin:device:lightswitch:1"
+	LightURN       = "urn:Belkin:device:light:1"
+)
+
+// DeviceType describes a kind of WeMo device.  New wraps a Device in the type
+// that exposes its services e.g. *Insight
+type DeviceType struct {
+	Name string
+	New  func(*Device) interface{}
+}
+
+func generic(d *Device) interface{} {
+	return d
+}
+
+var (
+	deviceTypesMutex sync.Mutex
+	deviceTypes      = map[string]DeviceType{}
+)
+
+func init() {
+	RegisterDeviceType(SwitchURN, DeviceType{"Switch", generic})
+	RegisterDeviceType(LightSwitchURN, DeviceType{"Light Switch", generic})
+	RegisterDeviceType(LightURN, DeviceType{"Light", generic})
+	RegisterDeviceType(InsightURN, DeviceType{"Insight", func(d *Device) interface{} { return &Insight{Device: d} }})
+	RegisterDeviceType(SensorURN, DeviceType{"Motion", func(d *Device) interface{} { return &MotionSensor{Device: d} }})
+	RegisterDeviceType(MakerURN, DeviceType{"Maker", func(d *Device) interface{} { return &Maker{Device: d} }})
+	RegisterDeviceType(BridgeURN, DeviceType{"Bridge", func(d *Device) interface{} { return &Bridge{Device: d} }})
+	RegisterDeviceType(DimmerURN, DeviceType{"Dimmer", func(d *Device) interface{} { return &Dimmer{Device: d} }})
+	RegisterDeviceType(CrockpotURN, DeviceType{"Crock-Pot", func(d *Device) interface{} { return &Crockpot{Device: d} }})
+	RegisterDeviceType(CoffeeMakerURN, DeviceType{"Coffee Maker", func(d *Device) interface{} { return &CoffeeMaker{Device: d} }})
+	RegisterDeviceType(HeaterURN, DeviceType{"Heater", func(d *Device) interface{} { return &Heater{Device: d} }})
+	RegisterDeviceType(HumidifierURN, DeviceType{"Humidifier", func(d *Device) interface{} { return &Humidifier{Device: d} }})
+	RegisterDeviceType(AirPurifierURN, DeviceType{"Air Purifier", func(d *Device) interface{} { return &AirPurifier{Device: d} }})
+}
+
+// deviceTypeKey drops the version and case from a URN so
+// urn:Belkin:device:Insight:2 matches urn:Belkin:device:insight:1
+func deviceTypeKey(urn string) string {
+	parts := strings.Split(urn, ":")
+	if len(parts) == 5 {
+		parts = parts[:4]
+	}
+	return strings.ToLower(strings.Join(parts, ":"))
+}
+
+// RegisterDeviceType adds or replaces the type used for devices advertising urn
+func RegisterDeviceType(urn string, deviceType DeviceType) {
+	deviceTypesMutex.Lock()
+	defer deviceTypesMutex.Unlock()
+
+	deviceTypes[deviceTypeKey(urn)] = deviceType
+}
+
+// LookupDeviceType returns the registered type for urn.  Unknown devices get
+// a generic type named after the URN
+func LookupDeviceType(urn string) (DeviceType, bool) {
+	deviceTypesMutex.Lock()
+	defer deviceTypesMutex.Unlock()
+
+	if deviceType, ok := deviceTypes[deviceTypeKey(urn)]; ok {
+		return deviceType, true
+	}
+
+	name := urn
+	if parts := strings.Split(urn, ":"); len(parts) >= 4 {
+		name = parts[3]
+	}
+	return DeviceType{Name: name, New: generic}, false
+}
+
+// Type returns the kind of device described by setup.xml
+func (d *DeviceInfo) Type() DeviceType {
+	deviceType, _ := LookupDeviceType(d.DeviceType)
+	return deviceType
+}
+
+// Typed returns the device wrapped in the type registered for its deviceType
+// e.g. *Insight, or the *Device itself for switches and unknown devices
+func (d *DeviceInfo) Typed() interface{} {
+	return d.Type().New(d.Device)
+}
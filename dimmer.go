@@ -0,0 +1,105 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DimmerURN = "urn:Belkin:device:dimmer:1"
+
+// Dimmer is a wall switch whose brightness can be set from 1 to 100 percent
+type Dimmer struct {
+	*Device
+}
+
+// NightMode limits the brightness the dimmer turns on to between Start and
+// End, both measured from midnight
+type NightMode struct {
+	Enabled    bool
+	Start      time.Duration
+	End        time.Duration
+	Brightness int
+}
+
+// SetBrightness turns the dimmer on at percent; 0 turns it off
+func (d *Dimmer) SetBrightness(ctx context.Context, percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("brightness must be between 0 and 100 => %d", percent)
+	}
+
+	args := struct {
+		BinaryState int
+		Brightness  int `xml:"brightness,omitempty"`
+	}{}
+	if percent > 0 {
+		args.BinaryState = 1
+		args.Brightness = percent
+	}
+
+	_, err := d.Call(ctx, ServiceBasicEvent, "SetBinaryState", args)
+	return err
+}
+
+// Brightness returns the current brightness in percent, or 0 when the dimmer is off
+func (d *Dimmer) Brightness(ctx context.Context) (int, error) {
+	response, err := d.Call(ctx, ServiceBasicEvent, "GetBinaryState", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	state, err := parseBinaryState(response.Args["BinaryState"])
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse BinaryState => %s", response.Args["BinaryState"])
+	}
+	if state == 0 {
+		return 0, nil
+	}
+
+	brightness, err := strconv.Atoi(strings.TrimSpace(response.Args["brightness"]))
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse brightness => %s", response.Args["brightness"])
+	}
+	return brightness, nil
+}
+
+func (d *Dimmer) ConfigureNightMode(ctx context.Context, mode NightMode) error {
+	if mode.Brightness < 1 || mode.Brightness > 100 {
+		return fmt.Errorf("night mode brightness must be between 1 and 100 => %d", mode.Brightness)
+	}
+
+	enabled := 0
+	if mode.Enabled {
+		enabled = 1
+	}
+
+	args := struct {
+		StartTime           int `xml:"startTime"`
+		NightMode           int `xml:"nightMode"`
+		EndTime             int `xml:"endTime"`
+		NightModeBrightness int `xml:"nightModeBrightness"`
+	}{
+		StartTime:           int(mode.Start / time.Second),
+		NightMode:           enabled,
+		EndTime:             int(mode.End / time.Second),
+		NightModeBrightness: mode.Brightness,
+	}
+
+	_, err := d.Call(ctx, ServiceBasicEvent, "ConfigureNightMode", args)
+	return err
+}
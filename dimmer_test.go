@@ -0,0 +1,114 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestDimmer(t *testing.T) {
+	Convey("Given a Dimmer", t, func() {
+		state := map[string]string{"BinaryState": "0", "brightness": "100"}
+		fake := &fakeDevice{
+			DeviceType: DimmerURN,
+			Services:   []string{ServiceBasicEvent},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				switch action {
+				case "GetBinaryState":
+					return state, nil
+				case "SetBinaryState":
+					state = map[string]string{"BinaryState": args["BinaryState"], "brightness": args["brightness"]}
+				}
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		dimmer := &Dimmer{Device: device}
+		ctx := context.Background()
+
+		Convey("When I call #SetBrightness", func() {
+			err := dimmer.SetBrightness(ctx, 40)
+
+			Convey("Then I expect the dimmer to be on at 40%", func() {
+				So(err, ShouldBeNil)
+				So(state["BinaryState"], ShouldEqual, "1")
+				So(state["brightness"], ShouldEqual, "40")
+
+				brightness, err := dimmer.Brightness(ctx)
+				So(err, ShouldBeNil)
+				So(brightness, ShouldEqual, 40)
+			})
+		})
+
+		Convey("When the dimmer is off", func() {
+			brightness, err := dimmer.Brightness(ctx)
+
+			Convey("Then I expect a brightness of 0", func() {
+				So(err, ShouldBeNil)
+				So(brightness, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I call #SetBrightness out of range", func() {
+			err := dimmer.SetBrightness(ctx, 140)
+
+			Convey("Then I expect an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestWemoSetBrightness(t *testing.T) {
+	Convey("Given a dimmer on the network", t, func() {
+		fake := &fakeDevice{
+			DeviceType: DimmerURN,
+			UDN:        "uuid:Dimmer-1_0-221326K0101234",
+			Services:   []string{ServiceBasicEvent},
+		}
+		device, stopDevice := fake.start()
+		defer stopDevice()
+
+		addr, stop := fakeResponder(device.Host)
+		defer stop()
+
+		saved := ssdpAddr
+		ssdpAddr = addr
+		defer func() { ssdpAddr = saved }()
+
+		api := NewByIp("127.0.0.1")
+
+		Convey("When I set a brightness out of range", func() {
+			err := api.SetBrightness("Porch", 150, 300*time.Millisecond)
+
+			Convey("Then I expect an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When no device has the name", func() {
+			err := api.SetBrightness("Porch", 50, 300*time.Millisecond)
+
+			Convey("Then I expect an error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "Porch")
+			})
+		})
+	})
+}
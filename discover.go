@@ -14,7 +14,13 @@
 package wemo
 
 import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"log"
+	"net/url"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,39 +29,253 @@ var belkinRE *regexp.Regexp = regexp.MustCompile(`http://([^/]+)/setup.xml`)
 type Wemo struct {
 	ipAddr string
 	Debug  bool
+
+	// auto discovers on every suitable interface; see NewAuto
+	auto bool
+
+	// Include and Exclude limit the interfaces used by NewAuto.  Entries are
+	// interface names e.g. en0 or subnets e.g. 10.0.1.0/24
+	Include []string
+	Exclude []string
+}
+
+// defaultURNs finds every root device; replies that aren't from Belkin are
+// dropped once their setup.xml is read
+var defaultURNs = []string{
+	"upnp:rootdevice",
+	"ssdp:all",
 }
 
 func (self *Wemo) DiscoverAll(timeout time.Duration) ([]*Device, error) {
-	urns := []string{
-		"urn:Belkin:device:controllee:1",
-		"urn:Belkin:device:light:1",
-		"urn:Belkin:device:sensor:1",
+	return self.discover(DiscoverOptions{}, timeout)
+}
+
+type DiscoverOptions struct {
+	// URNs to search for; defaults to every root device
+	URNs []string
+
+	// Expected stops discovery once this many devices are found; 0 waits
+	// until the context is done
+	Expected int
+}
+
+type DiscoveredDevice struct {
+	*Device
+	URN      string
+	USN      string
+	Location *url.URL
+	Response *SSDPResponse
+
+	// Info is the device's setup.xml
+	Info *DeviceInfo
+}
+
+// udn returns the uuid portion of a USN e.g.
+// uuid:Socket-1_0-221326K0101234::urn:Belkin:device:controllee:1
+func udn(usn string) string {
+	if index := strings.Index(usn, "::"); index >= 0 {
+		return usn[:index]
 	}
+	return usn
+}
+
+// DiscoverStream sends an M-SEARCH for each URN at once, on each address when
+// created by NewAuto, and reports each device as soon as its setup.xml
+// confirms it's a Belkin device.  Only verified devices are reported, so
+// Info is always set; a device that answers but whose setup.xml can't be read
+// before ctx is done is never sent.  The channel is closed when ctx is done or
+// opts.Expected devices have been found.  Errors are only logged, when Debug
+// is set; DiscoverAll and DiscoverDeviceInfos return them
+func (self *Wemo) DiscoverStream(ctx context.Context, opts DiscoverOptions) <-chan DiscoveredDevice {
+	ch, _ := self.discoverStream(ctx, opts)
+	return ch
+}
 
-	var all []*Device
-	for _, urn := range urns {
-		devices, _ := self.Discover(urn, timeout)
-		for _, device := range devices {
-			all = append(all, device)
+// discoverStream is DiscoverStream plus a func that, once the channel is
+// closed, returns why discovery failed: the address lookup or, when every
+// address failed to scan, each address's error
+func (self *Wemo) discoverStream(ctx context.Context, opts DiscoverOptions) (<-chan DiscoveredDevice, func() error) {
+	urns := opts.URNs
+	if len(urns) == 0 {
+		urns = defaultURNs
+	}
+
+	ch := make(chan DiscoveredDevice, 16)
+
+	ipAddrs, err := self.ipAddrs()
+	if err != nil {
+		if self.Debug {
+			log.Printf("discovery failed => %s\n", err)
 		}
+		close(ch)
+		return ch, func() error { return err }
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	responses := make(chan DiscoveredDevice)
+
+	// scanErr is only written before responses is closed, and so is safe to
+	// read once ch is closed
+	var scanErr error
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var failures []string
+	for _, ipAddr := range ipAddrs {
+		wg.Add(1)
+		go func(ipAddr string) {
+			defer wg.Done()
+			if err := self.scan(ctx, ipAddr, urns, responses); err != nil {
+				if self.Debug {
+					log.Printf("discovery on %s failed => %s\n", ipAddr, err)
+				}
+				mutex.Lock()
+				failures = append(failures, fmt.Sprintf("%s => %s", ipAddr, err))
+				mutex.Unlock()
+			}
+		}(ipAddr)
 	}
+	go func() {
+		wg.Wait()
+		if len(failures) == len(ipAddrs) {
+			scanErr = fmt.Errorf("discovery failed on every address: %s", strings.Join(failures, "; "))
+		}
+		close(responses)
+	}()
+
+	verified := make(chan DiscoveredDevice)
+	go func() {
+		var pending sync.WaitGroup
+		defer close(verified)
+		defer pending.Wait()
+
+		// a key is done once verified or found not to be Belkin; a failed
+		// fetch is retried on the device's next reply
+		var mutex sync.Mutex
+		done := map[string]bool{}
+		inflight := map[string]bool{}
+
+		for found := range responses {
+			// drain the remaining responses once done
+			if ctx.Err() != nil {
+				continue
+			}
+
+			key := udn(found.USN)
+			if key == "" {
+				key = found.Device.Host
+			}
+
+			mutex.Lock()
+			skip := done[key] || inflight[key]
+			inflight[key] = true
+			mutex.Unlock()
+			if skip {
+				continue
+			}
+
+			pending.Add(1)
+			go func(key string, found DiscoveredDevice) {
+				defer pending.Done()
 
-	return all, nil
+				ok, err := self.verify(ctx, &found)
+				mutex.Lock()
+				delete(inflight, key)
+				done[key] = err == nil
+				mutex.Unlock()
+
+				if ok {
+					select {
+					case verified <- found:
+					case <-ctx.Done():
+					}
+				}
+			}(key, found)
+		}
+	}()
+
+	go func() {
+		defer close(ch)
+		defer cancel()
+
+		count := 0
+		for found := range verified {
+			if ctx.Err() != nil {
+				continue
+			}
+
+			select {
+			case ch <- found:
+				count++
+			case <-ctx.Done():
+			}
+
+			if opts.Expected > 0 && count >= opts.Expected {
+				cancel()
+			}
+		}
+	}()
+
+	return ch, func() error { return scanErr }
+}
+
+// verify fetches setup.xml to confirm found is a Belkin device.  err is set
+// when setup.xml couldn't be read, in which case it's worth asking again
+func (self *Wemo) verify(ctx context.Context, found *DiscoveredDevice) (bool, error) {
+	deviceInfo, err := fetchDeviceInfo(ctx, found.Device.Host)
+	if err != nil {
+		if self.Debug {
+			log.Printf("unable to fetch setup.xml from %s => %s\n", found.Device.Host, err)
+		}
+		return false, err
+	}
+	if !isBelkinDevice(deviceInfo) {
+		return false, nil
+	}
+
+	found.Device.services = deviceInfo.Services
+	found.Device.udn = deviceInfo.UDN
+	deviceInfo.Device = found.Device
+	found.Info = deviceInfo
+	return true, nil
 }
 
 func (self *Wemo) Discover(urn string, timeout time.Duration) ([]*Device, error) {
-	locations, err := self.scan(urn, timeout)
+	return self.discover(DiscoverOptions{URNs: []string{urn}}, timeout)
+}
+
+func (self *Wemo) discover(opts DiscoverOptions, timeout time.Duration) ([]*Device, error) {
+	deviceInfos, err := self.discoverDeviceInfos(opts, timeout)
 	if err != nil {
 		return nil, err
 	}
 
 	var devices []*Device
-	for _, uri := range locations {
-		if matches := belkinRE.FindStringSubmatch(uri.String()); len(matches) == 2 {
-			host := matches[1]
-			devices = append(devices, &Device{Host: host})
-		}
+	for _, deviceInfo := range deviceInfos {
+		devices = append(devices, deviceInfo.Device)
 	}
 
 	return devices, nil
 }
+
+// DiscoverDeviceInfos is like DiscoverAll but returns the setup.xml of each
+// device, which DeviceInfo.Typed turns into the matching type e.g. *Insight
+func (self *Wemo) DiscoverDeviceInfos(timeout time.Duration) (DeviceInfos, error) {
+	return self.discoverDeviceInfos(DiscoverOptions{}, timeout)
+}
+
+func (self *Wemo) discoverDeviceInfos(opts DiscoverOptions, timeout time.Duration) (DeviceInfos, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ch, errFn := self.discoverStream(ctx, opts)
+
+	deviceInfos := DeviceInfos{}
+	for found := range ch {
+		deviceInfos = append(deviceInfos, found.Info)
+	}
+	if err := errFn(); err != nil {
+		return nil, err
+	}
+
+	return deviceInfos, nil
+}
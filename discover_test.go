@@ -14,9 +14,17 @@
 package wemo
 
 import (
+	"bufio"
+	"bytes"
+	"code.google.com/p/go.net/context"
 	"fmt"
 	. "github.com/smartystreets/goconvey/convey"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -77,3 +85,190 @@ func TestREMatch(t *testing.T) {
 		})
 	})
 }
+
+// fakeResponder answers each M-SEARCH with a malformed packet and then for
+// each host, once right away and again shortly after, as devices do
+func fakeResponder(hosts ...string) (string, func()) {
+	conn, _ := net.ListenPacket("udp4", "127.0.0.1:0")
+	go func() {
+		buffer := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buffer[:n])))
+			if err != nil {
+				continue
+			}
+			st := req.Header.Get("ST")
+			conn.WriteTo([]byte("HTTP/1.1 200 OK\r\nLOCATION: http://[::1\r\n\r\n"), addr)
+			for i, host := range hosts {
+				response := fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+					"CACHE-CONTROL: max-age=86400\r\n"+
+					"LOCATION: http://%s/setup.xml\r\n"+
+					"ST: %s\r\n"+
+					"USN: uuid:device-%d::%s\r\n"+
+					"\r\n", host, st, i, st)
+				conn.WriteTo([]byte(response), addr)
+				time.AfterFunc(100*time.Millisecond, func() {
+					conn.WriteTo([]byte(response), addr)
+				})
+			}
+		}
+	}()
+	return conn.LocalAddr().String(), func() { conn.Close() }
+}
+
+func TestDiscoverStream(t *testing.T) {
+	Convey("Given a device and a router answering searches", t, func() {
+		fake := &fakeDevice{
+			DeviceType: InsightURN,
+			UDN:        "uuid:Insight-1_0-221326K0101234",
+			Services:   []string{ServiceBasicEvent},
+		}
+		device, stopDevice := fake.start()
+		defer stopDevice()
+
+		router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte("<root><device><deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType><UDN>uuid:router</UDN></device></root>"))
+		}))
+		defer router.Close()
+
+		addr, stop := fakeResponder(device.Host, strings.TrimPrefix(router.URL, "http://"))
+		defer stop()
+
+		saved := ssdpAddr
+		ssdpAddr = addr
+		defer func() { ssdpAddr = saved }()
+
+		api := NewByIp("127.0.0.1")
+
+		Convey("When I search for root devices", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			var found []DiscoveredDevice
+			for device := range api.DiscoverStream(ctx, DiscoverOptions{}) {
+				found = append(found, device)
+			}
+
+			Convey("Then I expect only the Belkin device, once", func() {
+				So(len(found), ShouldEqual, 1)
+				So(found[0].Host, ShouldEqual, device.Host)
+				So(found[0].USN, ShouldStartWith, "uuid:device-0::")
+			})
+
+			Convey("Then I expect it to be classified by its deviceType", func() {
+				So(found[0].Info.DeviceType, ShouldEqual, InsightURN)
+				So(found[0].Info.Type().Name, ShouldEqual, "Insight")
+
+				insight, ok := found[0].Info.Typed().(*Insight)
+				So(ok, ShouldBeTrue)
+				So(insight.Device, ShouldEqual, found[0].Device)
+			})
+		})
+
+		Convey("When I expect a single device", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			started := time.Now()
+			var found []DiscoveredDevice
+			for device := range api.DiscoverStream(ctx, DiscoverOptions{Expected: 1}) {
+				found = append(found, device)
+			}
+
+			Convey("Then I expect discovery to stop without waiting for the context", func() {
+				So(len(found), ShouldEqual, 1)
+				So(time.Since(started), ShouldBeLessThan, time.Second)
+			})
+		})
+	})
+}
+
+func TestDiscoverStreamRetry(t *testing.T) {
+	Convey("Given a device whose first setup.xml request fails", t, func() {
+		fake := &fakeDevice{
+			DeviceType: InsightURN,
+			UDN:        "uuid:Insight-1_0-221326K0101234",
+			Services:   []string{ServiceBasicEvent},
+		}
+
+		var mutex sync.Mutex
+		failed := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mutex.Lock()
+			fail := !failed && req.URL.Path == "/setup.xml"
+			failed = true
+			mutex.Unlock()
+
+			if fail {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fake.ServeHTTP(w, req)
+		}))
+		defer server.Close()
+
+		addr, stop := fakeResponder(strings.TrimPrefix(server.URL, "http://"))
+		defer stop()
+
+		saved := ssdpAddr
+		ssdpAddr = addr
+		defer func() { ssdpAddr = saved }()
+
+		Convey("When I search with a single URN", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			var found []DiscoveredDevice
+			for device := range NewByIp("127.0.0.1").DiscoverStream(ctx, DiscoverOptions{URNs: []string{InsightURN}}) {
+				found = append(found, device)
+			}
+
+			Convey("Then I expect the device's next reply to be verified", func() {
+				So(len(found), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestDiscoverErrors(t *testing.T) {
+	Convey("Given an address that can't be bound", t, func() {
+		api := NewByIp("192.0.2.1")
+
+		Convey("When I call #DiscoverAll", func() {
+			devices, err := api.DiscoverAll(200 * time.Millisecond)
+
+			Convey("Then I expect the scan error rather than an empty result", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "192.0.2.1")
+				So(len(devices), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestLookupDeviceType(t *testing.T) {
+	Convey("Given a newer version of a known URN", t, func() {
+		deviceType, ok := LookupDeviceType("urn:Belkin:device:Insight:2")
+
+		Convey("Then I expect the registered type", func() {
+			So(ok, ShouldBeTrue)
+			So(deviceType.Name, ShouldEqual, "Insight")
+		})
+	})
+
+	Convey("Given an unknown URN", t, func() {
+		deviceType, ok := LookupDeviceType("urn:Belkin:device:OutdoorPlug:1")
+
+		Convey("Then I expect a generic device", func() {
+			So(ok, ShouldBeFalse)
+			So(deviceType.Name, ShouldEqual, "OutdoorPlug")
+
+			device := &Device{}
+			So(deviceType.New(device), ShouldEqual, device)
+		})
+	})
+}
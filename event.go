@@ -0,0 +1,89 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a single state variable change delivered by a device through a
+// GENA NOTIFY e.g. Name: BinaryState, Value: 1
+type Event struct {
+	Device      *Device
+	ServiceType string
+	SID         string
+	Seq         int
+	Name        string
+	Value       string
+	Time        time.Time
+}
+
+// BinaryState returns the state carried by a BinaryState event.  Insight
+// devices append their params to the state e.g. 8|1414583000|..., only the
+// leading state is returned
+func (e Event) BinaryState() (int, bool) {
+	if e.Name != "BinaryState" {
+		return 0, false
+	}
+
+	state, err := parseBinaryState(e.Value)
+	if err != nil {
+		return 0, false
+	}
+	return state, true
+}
+
+func parseBinaryState(value string) (int, error) {
+	if index := strings.Index(value, "|"); index >= 0 {
+		value = value[:index]
+	}
+	return strconv.Atoi(strings.TrimSpace(value))
+}
+
+type property struct {
+	Name  string
+	Value string
+}
+
+// unmarshalPropertySet parses the body of a NOTIFY e.g.
+// <e:propertyset><e:property><BinaryState>1</BinaryState></e:property></e:propertyset>
+func unmarshalPropertySet(data []byte) ([]property, error) {
+	propertySet := struct {
+		Properties []struct {
+			Variables []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"property"`
+	}{}
+	err := xml.Unmarshal(data, &propertySet)
+	if err != nil {
+		return nil, err
+	}
+
+	var properties []property
+	for _, p := range propertySet.Properties {
+		for _, variable := range p.Variables {
+			properties = append(properties, property{
+				Name:  variable.XMLName.Local,
+				Value: variable.Value,
+			})
+		}
+	}
+
+	return properties, nil
+}
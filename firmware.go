@@ -0,0 +1,202 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type FirmwareInfo struct {
+	Version string
+	SkuNo   string
+}
+
+// parseFirmwareVersion parses GetFirmwareVersion e.g.
+// FirmwareVersion:WeMo_WW_2.00.11057.PVT-OWRT-SNS|SkuNo:Plugin Device
+func parseFirmwareVersion(value string) *FirmwareInfo {
+	info := &FirmwareInfo{}
+	for _, field := range strings.Split(value, "|") {
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		switch strings.TrimSpace(parts[0]) {
+		case "FirmwareVersion":
+			info.Version = strings.TrimSpace(parts[1])
+		case "SkuNo":
+			info.SkuNo = strings.TrimSpace(parts[1])
+		}
+	}
+	return info
+}
+
+func (d *Device) FirmwareVersion(ctx context.Context) (*FirmwareInfo, error) {
+	response, err := d.Call(ctx, ServiceFirmwareUpdate, "GetFirmwareVersion", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFirmwareVersion(response.Args["FirmwareVersion"]), nil
+}
+
+type FirmwareUpdate struct {
+	Version     string
+	ReleaseDate string
+	URL         string
+	Signature   string
+
+	// Unsigned must be set when the image carries no signature
+	Unsigned bool
+
+	// Delay before the device starts downloading
+	Delay time.Duration
+}
+
+// UpdateFirmware tells the device to download and flash the image at
+// update.URL.  The device reboots once it's done, which takes a few minutes
+func (d *Device) UpdateFirmware(ctx context.Context, update FirmwareUpdate) error {
+	if update.URL == "" {
+		return fmt.Errorf("firmware update requires a URL")
+	}
+
+	unsigned := 0
+	if update.Unsigned {
+		unsigned = 1
+	}
+
+	args := struct {
+		NewFirmwareVersion string
+		ReleaseDate        string
+		URL                string
+		Signature          string
+		DownloadStartTime  int
+		WithUnsignedImage  int
+	}{
+		NewFirmwareVersion: update.Version,
+		ReleaseDate:        update.ReleaseDate,
+		URL:                update.URL,
+		Signature:          update.Signature,
+		DownloadStartTime:  int(update.Delay / time.Second),
+		WithUnsignedImage:  unsigned,
+	}
+
+	_, err := d.Call(ctx, ServiceFirmwareUpdate, "UpdateFirmware", args)
+	return err
+}
+
+// FirmwareServer serves a firmware image from local disk so devices can be
+// updated without Belkin's cloud
+type FirmwareServer struct {
+	listener net.Listener
+	path     string
+	name     string
+
+	once       sync.Once
+	downloaded chan struct{}
+}
+
+// ServeFirmware serves file on addr, which must be reachable from the device
+// e.g. 10.0.1.5 or 10.0.1.5:8080
+func ServeFirmware(addr, file string) (*FirmwareServer, error) {
+	if _, err := os.Stat(file); err != nil {
+		return nil, err
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "0")
+	}
+
+	listener, err := net.Listen("tcp4", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FirmwareServer{
+		listener:   listener,
+		path:       file,
+		name:       filepath.Base(file),
+		downloaded: make(chan struct{}),
+	}
+	go http.Serve(listener, s)
+
+	return s, nil
+}
+
+// URL to pass in FirmwareUpdate.URL
+func (s *FirmwareServer) URL() string {
+	u := &url.URL{Scheme: "http", Host: s.listener.Addr().String(), Path: "/" + s.name}
+	return u.String()
+}
+
+// Downloaded is closed once a device has fetched the whole image
+func (s *FirmwareServer) Downloaded() <-chan struct{} {
+	return s.downloaded
+}
+
+func (s *FirmwareServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/"+s.name {
+		http.NotFound(w, req)
+		return
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// only a complete 200 counts; ranges, HEADs and aborted transfers don't
+	rw := &downloadWriter{ResponseWriter: w}
+	http.ServeFile(rw, req, s.path)
+	if req.Method == "GET" && rw.status == http.StatusOK && rw.written == info.Size() {
+		s.once.Do(func() { close(s.downloaded) })
+	}
+}
+
+// downloadWriter records what was actually sent back to the device
+type downloadWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (w *downloadWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *downloadWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.written += int64(n)
+	return n, err
+}
+
+func (s *FirmwareServer) Close() error {
+	return s.listener.Close()
+}
@@ -0,0 +1,108 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFirmware(t *testing.T) {
+	Convey("Given a GetFirmwareVersion value", t, func() {
+		value := "FirmwareVersion:WeMo_WW_2.00.11057.PVT-OWRT-SNS|SkuNo:Plugin Device"
+
+		Convey("Then I expect the version and sku to be parsed", func() {
+			info := parseFirmwareVersion(value)
+			So(info.Version, ShouldEqual, "WeMo_WW_2.00.11057.PVT-OWRT-SNS")
+			So(info.SkuNo, ShouldEqual, "Plugin Device")
+		})
+	})
+
+	Convey("Given a firmware image on disk", t, func() {
+		file, _ := ioutil.TempFile("", "firmware image-")
+		file.Write([]byte("firmware image"))
+		file.Close()
+		defer os.Remove(file.Name())
+
+		server, err := ServeFirmware("127.0.0.1", file.Name())
+		So(err, ShouldBeNil)
+		defer server.Close()
+
+		var update map[string]string
+		fake := &fakeDevice{
+			DeviceType: "urn:Belkin:device:controllee:1",
+			Services:   []string{ServiceFirmwareUpdate},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				update = args
+				go func() {
+					if response, err := http.Get(args["URL"]); err == nil {
+						ioutil.ReadAll(response.Body)
+						response.Body.Close()
+					}
+				}()
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		Convey("When I push it to a device", func() {
+			err := device.UpdateFirmware(context.Background(), FirmwareUpdate{
+				Version:  "WeMo_WW_2.00.11057.PVT-OWRT-SNS",
+				URL:      server.URL(),
+				Unsigned: true,
+			})
+
+			Convey("Then I expect the device to be told where to download it", func() {
+				So(err, ShouldBeNil)
+				So(update["URL"], ShouldEqual, server.URL())
+				So(update["WithUnsignedImage"], ShouldEqual, "1")
+			})
+
+			Convey("Then I expect to be told once the device downloads it", func() {
+				select {
+				case <-server.Downloaded():
+				case <-time.After(2 * time.Second):
+					So("timed out waiting for download", ShouldBeEmpty)
+				}
+			})
+		})
+
+		Convey("When only part of the image, or the wrong path, is fetched", func() {
+			request, _ := http.NewRequest("GET", server.URL(), nil)
+			request.Header.Set("Range", "bytes=0-3")
+			if response, err := http.DefaultClient.Do(request); err == nil {
+				ioutil.ReadAll(response.Body)
+				response.Body.Close()
+			}
+			if response, err := http.Get(server.URL() + ".bak"); err == nil {
+				ioutil.ReadAll(response.Body)
+				response.Body.Close()
+			}
+
+			Convey("Then I expect Downloaded to stay open", func() {
+				select {
+				case <-server.Downloaded():
+					So("unexpected download", ShouldBeEmpty)
+				case <-time.After(100 * time.Millisecond):
+				}
+			})
+		})
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const HeaterURN = "urn:Belkin:device:HeaterA:1"
+
+type HeaterMode int
+
+const (
+	HeaterOff          HeaterMode = 0
+	HeaterFrostProtect HeaterMode = 1
+	HeaterHigh         HeaterMode = 2
+	HeaterLow          HeaterMode = 3
+	HeaterEco          HeaterMode = 4
+)
+
+var heaterModes = map[HeaterMode]string{
+	HeaterOff:          "off",
+	HeaterFrostProtect: "frostprotect",
+	HeaterHigh:         "high",
+	HeaterLow:          "low",
+	HeaterEco:          "eco",
+}
+
+func (m HeaterMode) String() string {
+	if name, ok := heaterModes[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("HeaterMode(%d)", int(m))
+}
+
+type HeaterState struct {
+	Mode              HeaterMode    `json:"mode"`
+	Temperature       float64       `json:"temperature"`
+	TargetTemperature float64       `json:"target-temperature"`
+	Fahrenheit        bool          `json:"fahrenheit"`
+	AutoOffTime       time.Duration `json:"auto-off-time"`
+	TimeRemaining     time.Duration `json:"time-remaining"`
+}
+
+// Heater is the WeMo enabled Holmes space heater.  Temperatures are in
+// whichever unit the heater is configured for; see HeaterState.Fahrenheit
+type Heater struct {
+	*Device
+}
+
+func (h *Heater) State(ctx context.Context) (*HeaterState, error) {
+	attributes, err := h.GetAttributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HeaterState{
+		Mode:              HeaterMode(attributeInt(attributes, "Mode")),
+		Temperature:       attributeFloat(attributes, "Temperature"),
+		TargetTemperature: attributeFloat(attributes, "SetTemperature"),
+		Fahrenheit:        attributes["TempUnit"] == "1",
+		AutoOffTime:       time.Duration(attributeInt(attributes, "AutoOffTime")) * time.Second,
+		TimeRemaining:     time.Duration(attributeInt(attributes, "TimeRemaining")) * time.Second,
+	}, nil
+}
+
+func (h *Heater) SetMode(ctx context.Context, mode HeaterMode) error {
+	if _, ok := heaterModes[mode]; !ok {
+		return fmt.Errorf("unknown heater mode => %d", int(mode))
+	}
+
+	return h.SetAttributes(ctx, map[string]string{
+		"Mode": strconv.Itoa(int(mode)),
+	})
+}
+
+func (h *Heater) SetTargetTemperature(ctx context.Context, temperature float64) error {
+	return h.SetAttributes(ctx, map[string]string{
+		"SetTemperature": strconv.FormatFloat(temperature, 'f', 1, 64),
+	})
+}
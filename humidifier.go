@@ -0,0 +1,114 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"strconv"
+)
+
+const HumidifierURN = "urn:Belkin:device:Humidifier:1"
+
+type FanSpeed int
+
+const (
+	FanOff     FanSpeed = 0
+	FanMinimum FanSpeed = 1
+	FanLow     FanSpeed = 2
+	FanMedium  FanSpeed = 3
+	FanHigh    FanSpeed = 4
+	FanMaximum FanSpeed = 5
+)
+
+var fanSpeeds = map[FanSpeed]string{
+	FanOff:     "off",
+	FanMinimum: "minimum",
+	FanLow:     "low",
+	FanMedium:  "medium",
+	FanHigh:    "high",
+	FanMaximum: "maximum",
+}
+
+func (f FanSpeed) String() string {
+	if name, ok := fanSpeeds[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("FanSpeed(%d)", int(f))
+}
+
+// the humidifier only accepts a handful of set points, encoded as an index
+var desiredHumidities = []int{45, 50, 55, 60, 100}
+
+// filter life is reported in minutes out of a total of 60480 (six weeks)
+const filterLifeMinutes = 60480
+
+type HumidifierState struct {
+	FanSpeed        FanSpeed `json:"fan-speed"`
+	DesiredHumidity int      `json:"desired-humidity"` // percent
+	CurrentHumidity float64  `json:"current-humidity"` // percent
+	NoWater         bool     `json:"no-water"`
+	WaterAdvise     bool     `json:"water-advise"`
+	FilterLife      float64  `json:"filter-life"` // percent remaining
+	FilterExpired   bool     `json:"filter-expired"`
+}
+
+// Humidifier is the WeMo enabled Holmes humidifier
+type Humidifier struct {
+	*Device
+}
+
+func (h *Humidifier) State(ctx context.Context) (*HumidifierState, error) {
+	attributes, err := h.GetAttributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := 0
+	if index := attributeInt(attributes, "DesiredHumidity"); index >= 0 && index < len(desiredHumidities) {
+		desired = desiredHumidities[index]
+	}
+
+	return &HumidifierState{
+		FanSpeed:        FanSpeed(attributeInt(attributes, "FanMode")),
+		DesiredHumidity: desired,
+		CurrentHumidity: attributeFloat(attributes, "CurrentHumidity"),
+		NoWater:         attributes["NoWater"] == "1",
+		WaterAdvise:     attributes["WaterAdvise"] == "1",
+		FilterLife:      attributeFloat(attributes, "FilterLife") * 100 / filterLifeMinutes,
+		FilterExpired:   attributes["ExpiredFilterTime"] == "1",
+	}, nil
+}
+
+func (h *Humidifier) SetFanSpeed(ctx context.Context, speed FanSpeed) error {
+	if _, ok := fanSpeeds[speed]; !ok {
+		return fmt.Errorf("unknown fan speed => %d", int(speed))
+	}
+
+	return h.SetAttributes(ctx, map[string]string{
+		"FanMode": strconv.Itoa(int(speed)),
+	})
+}
+
+// SetDesiredHumidity accepts 45, 50, 55, 60 or 100 percent
+func (h *Humidifier) SetDesiredHumidity(ctx context.Context, percent int) error {
+	for index, humidity := range desiredHumidities {
+		if humidity == percent {
+			return h.SetAttributes(ctx, map[string]string{
+				"DesiredHumidity": strconv.Itoa(index),
+			})
+		}
+	}
+	return fmt.Errorf("desired humidity must be one of %v => %d", desiredHumidities, percent)
+}
@@ -0,0 +1,162 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	InsightURN     = "urn:Belkin:device:insight:1"
+	ServiceInsight = "urn:Belkin:service:insight:1"
+)
+
+// DefaultPowerThreshold is the factory standby threshold in watts
+const DefaultPowerThreshold = 8.0
+
+// binary states reported by an Insight
+const (
+	InsightOff     = 0
+	InsightOn      = 1
+	InsightStandby = 8
+)
+
+// InsightParams is the parsed form of GetInsightParams e.g.
+// 8|1414594356|0|0|4589|1209600|0|3965|1287305|29236546.000000|8000
+type InsightParams struct {
+	State        int           `json:"state"`
+	LastChange   time.Time     `json:"last-change"`
+	OnFor        time.Duration `json:"on-for"`
+	OnToday      time.Duration `json:"on-today"`
+	OnTotal      time.Duration `json:"on-total"`
+	TimePeriod   time.Duration `json:"time-period"`
+	AveragePower float64       `json:"average-power"` // whole watts, unlike the mW fields
+	CurrentPower float64       `json:"current-power"` // watts
+	TodayEnergy  float64       `json:"today-energy"`  // watt hours
+	TotalEnergy  float64       `json:"total-energy"`  // watt hours
+	Threshold    float64       `json:"threshold"`     // watts
+}
+
+func (p *InsightParams) On() bool {
+	return p.State == InsightOn || p.State == InsightStandby
+}
+
+func (p *InsightParams) Standby() bool {
+	return p.State == InsightStandby
+}
+
+// Insight is a switch that also meters the power drawn through it
+type Insight struct {
+	*Device
+}
+
+func parseInsightParams(value string) (*InsightParams, error) {
+	fields := strings.Split(strings.TrimSpace(value), "|")
+	if len(fields) < 11 {
+		return nil, fmt.Errorf("expected 11 insight params; got %d => %s", len(fields), value)
+	}
+
+	numbers := make([]float64, len(fields))
+	for i, field := range fields {
+		number, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse insight param %d => %s", i, value)
+		}
+		numbers[i] = number
+	}
+
+	seconds := func(v float64) time.Duration { return time.Duration(v) * time.Second }
+
+	// power is reported in milliwatts and energy in milliwatt minutes
+	return &InsightParams{
+		State:        int(numbers[0]),
+		LastChange:   time.Unix(int64(numbers[1]), 0),
+		OnFor:        seconds(numbers[2]),
+		OnToday:      seconds(numbers[3]),
+		OnTotal:      seconds(numbers[4]),
+		TimePeriod:   seconds(numbers[5]),
+		AveragePower: numbers[6],
+		CurrentPower: numbers[7] / 1000,
+		TodayEnergy:  numbers[8] / 1000 / 60,
+		TotalEnergy:  numbers[9] / 1000 / 60,
+		Threshold:    numbers[10] / 1000,
+	}, nil
+}
+
+// InsightParams fetches the current power readings
+func (i *Insight) InsightParams(ctx context.Context) (*InsightParams, error) {
+	response, err := i.Call(ctx, ServiceInsight, "GetInsightParams", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseInsightParams(response.Args["InsightParams"])
+}
+
+// PowerThreshold returns the power, in watts, below which the Insight reports standby
+func (i *Insight) PowerThreshold(ctx context.Context) (float64, error) {
+	response, err := i.Call(ctx, ServiceInsight, "GetPowerThreshold", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	milliwatts, err := strconv.ParseFloat(strings.TrimSpace(response.Args["PowerThreshold"]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse PowerThreshold => %s", response.Args["PowerThreshold"])
+	}
+	return milliwatts / 1000, nil
+}
+
+// SetPowerThreshold changes the standby threshold; watts are converted to the
+// milliwatts the device expects
+func (i *Insight) SetPowerThreshold(ctx context.Context, watts float64) error {
+	if watts < 0 {
+		return fmt.Errorf("power threshold must not be negative => %v", watts)
+	}
+
+	args := map[string]string{"PowerThreshold": strconv.Itoa(int(watts*1000 + 0.5))}
+	_, err := i.Call(ctx, ServiceInsight, "SetPowerThreshold", args)
+	return err
+}
+
+// SetAutoPowerThreshold lets the device learn the standby threshold from the
+// appliance currently plugged in
+func (i *Insight) SetAutoPowerThreshold(ctx context.Context) error {
+	_, err := i.Call(ctx, ServiceInsight, "SetAutoPowerThreshold", nil)
+	return err
+}
+
+// ResetPowerThreshold restores DefaultPowerThreshold
+func (i *Insight) ResetPowerThreshold(ctx context.Context) error {
+	args := map[string]string{"PowerThreshold": strconv.Itoa(int(DefaultPowerThreshold * 1000))}
+	_, err := i.Call(ctx, ServiceInsight, "ResetPowerThreshold", args)
+	return err
+}
+
+// InsightParams returns the readings carried by an InsightParams event
+func (e Event) InsightParams() (*InsightParams, bool) {
+	if e.Name != "InsightParams" {
+		return nil, false
+	}
+
+	params, err := parseInsightParams(e.Value)
+	if err != nil {
+		return nil, false
+	}
+	return params, true
+}
@@ -0,0 +1,129 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestParseInsightParams(t *testing.T) {
+	Convey("Given an InsightParams value", t, func() {
+		value := "8|1414594356|120|3600|4589|1209600|0|3965|1287305|29236546.000000|8000"
+
+		Convey("When I call #parseInsightParams", func() {
+			params, err := parseInsightParams(value)
+
+			Convey("Then I expect no errors", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then I expect the state to be standby", func() {
+				So(params.State, ShouldEqual, InsightStandby)
+				So(params.On(), ShouldBeTrue)
+				So(params.Standby(), ShouldBeTrue)
+			})
+
+			Convey("Then I expect times to be set", func() {
+				So(params.LastChange.Unix(), ShouldEqual, 1414594356)
+				So(params.OnFor, ShouldEqual, 2*time.Minute)
+				So(params.OnToday, ShouldEqual, time.Hour)
+			})
+
+			Convey("Then I expect power in watts", func() {
+				So(params.CurrentPower, ShouldAlmostEqual, 3.965, 0.0001)
+				So(params.Threshold, ShouldAlmostEqual, 8.0, 0.0001)
+			})
+
+			Convey("Then I expect energy in watt hours", func() {
+				So(params.TodayEnergy, ShouldAlmostEqual, 21.455, 0.001)
+				So(params.TotalEnergy, ShouldAlmostEqual, 487.276, 0.001)
+			})
+		})
+	})
+
+	Convey("Given an InsightParams value from a device drawing about 43 W", t, func() {
+		value := "1|1414537236|2357|9327|61337|1209600|44|43500|1035690|1035690.000000|8000"
+
+		Convey("When I call #parseInsightParams", func() {
+			params, err := parseInsightParams(value)
+
+			Convey("Then I expect the average power in watts, as reported", func() {
+				So(err, ShouldBeNil)
+				So(params.AveragePower, ShouldEqual, 44)
+				So(params.CurrentPower, ShouldAlmostEqual, 43.5, 0.0001)
+			})
+		})
+	})
+
+	Convey("Given a truncated value", t, func() {
+		Convey("Then I expect an error", func() {
+			_, err := parseInsightParams("8|1414594356")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestPowerThreshold(t *testing.T) {
+	Convey("Given an Insight", t, func() {
+		var threshold = "8000"
+		fake := &fakeDevice{
+			DeviceType: InsightURN,
+			Services:   []string{ServiceBasicEvent, ServiceInsight},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				switch action {
+				case "GetPowerThreshold":
+					return map[string]string{"PowerThreshold": threshold}, nil
+				case "SetPowerThreshold", "ResetPowerThreshold":
+					threshold = args["PowerThreshold"]
+				}
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		insight := &Insight{Device: device}
+		ctx := context.Background()
+
+		Convey("When I call #PowerThreshold", func() {
+			watts, err := insight.PowerThreshold(ctx)
+
+			Convey("Then I expect the threshold in watts", func() {
+				So(err, ShouldBeNil)
+				So(watts, ShouldEqual, 8.0)
+			})
+		})
+
+		Convey("When I call #SetPowerThreshold", func() {
+			err := insight.SetPowerThreshold(ctx, 12.5)
+
+			Convey("Then I expect milliwatts to be sent", func() {
+				So(err, ShouldBeNil)
+				So(threshold, ShouldEqual, "12500")
+			})
+
+			Convey("And I call #ResetPowerThreshold", func() {
+				err := insight.ResetPowerThreshold(ctx)
+
+				Convey("Then I expect the default to be restored", func() {
+					So(err, ShouldBeNil)
+					So(threshold, ShouldEqual, "8000")
+				})
+			})
+		})
+	})
+}
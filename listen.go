@@ -0,0 +1,277 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"bufio"
+	"bytes"
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// values of the NTS header of a NOTIFY
+const (
+	Alive  = "ssdp:alive"
+	ByeBye = "ssdp:byebye"
+	Update = "ssdp:update"
+)
+
+// Announcement is an unsolicited NOTIFY multicast by a device as it joins,
+// refreshes or leaves the network
+type Announcement struct {
+	Type     string
+	NT       string
+	USN      string
+	Location *url.URL
+
+	// Host of the device; empty for ssdp:byebye, which carries no LOCATION
+	Host   string
+	MaxAge time.Duration
+	Time   time.Time
+}
+
+// Device returns a Device for the announced host
+func (a Announcement) Device() *Device {
+	return &Device{Host: a.Host}
+}
+
+// parseMaxAge parses a CACHE-CONTROL header e.g. max-age=1800
+func parseMaxAge(value string) time.Duration {
+	for _, directive := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(directive), "=", 2)
+		if len(parts) != 2 || !strings.EqualFold(strings.TrimSpace(parts[0]), "max-age") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
+
+func isBelkin(values ...string) bool {
+	for _, value := range values {
+		if strings.Contains(value, "urn:Belkin:") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAnnouncement parses a NOTIFY packet.  ok is false for other requests
+// and for announcements from non Belkin devices
+func parseAnnouncement(data []byte) (Announcement, bool) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil || req.Method != "NOTIFY" {
+		return Announcement{}, false
+	}
+
+	announcement := Announcement{
+		Type:   req.Header.Get("NTS"),
+		NT:     req.Header.Get("NT"),
+		USN:    req.Header.Get("USN"),
+		MaxAge: parseMaxAge(req.Header.Get("CACHE-CONTROL")),
+		Time:   time.Now(),
+	}
+
+	if location := req.Header.Get("LOCATION"); location != "" {
+		u, err := url.Parse(location)
+		if err != nil {
+			return Announcement{}, false
+		}
+		announcement.Location = u
+
+		if matches := belkinRE.FindStringSubmatch(location); len(matches) == 2 {
+			announcement.Host = matches[1]
+		}
+	}
+
+	// a /setup.xml location alone isn't enough; plenty of other UPnP devices use it
+	if !isBelkin(announcement.NT, announcement.USN) {
+		return Announcement{}, false
+	}
+
+	return announcement, true
+}
+
+// interfaceByIp returns the interface that owns ipAddr or nil to let the
+// system choose
+func interfaceByIp(ipAddr string) (*net.Interface, error) {
+	if ipAddr == "" {
+		return nil, nil
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.String() == ipAddr {
+				iface := iface
+				return &iface, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unable to find interface with ip address, %s", ipAddr)
+}
+
+// duplicateWindow suppresses copies of a packet that arrive on several
+// interfaces at once
+var duplicateWindow = time.Second
+
+// listenInterfaces returns the interfaces to join the multicast group on; nil
+// lets the system choose
+func (self *Wemo) listenInterfaces() ([]*net.Interface, error) {
+	if !self.auto {
+		iface, err := interfaceByIp(self.ipAddr)
+		if err != nil {
+			return nil, err
+		}
+		return []*net.Interface{iface}, nil
+	}
+
+	ipAddrs, err := self.ipAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var ifaces []*net.Interface
+	for _, ipAddr := range ipAddrs {
+		iface, err := interfaceByIp(ipAddr)
+		if err != nil {
+			return nil, err
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return ifaces, nil
+}
+
+// Listen joins the SSDP multicast group, on every interface when created by
+// NewAuto, and reports announcements from Belkin devices until ctx is done,
+// at which point the channel is closed
+func (self *Wemo) Listen(ctx context.Context) (<-chan Announcement, error) {
+	ifaces, err := self.listenInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	mAddr, err := net.ResolveUDPAddr("udp4", SSDP_BROADCAST)
+	if err != nil {
+		return nil, err
+	}
+
+	var conns []net.PacketConn
+	for _, iface := range ifaces {
+		conn, err := net.ListenMulticastUDP("udp4", iface, mAddr)
+		if err != nil {
+			for _, conn := range conns {
+				conn.Close()
+			}
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+
+	announcements := make(chan Announcement, 16)
+	go self.readAnnouncements(ctx, conns, announcements)
+
+	return announcements, nil
+}
+
+func (self *Wemo) readAnnouncements(ctx context.Context, conns []net.PacketConn, announcements chan Announcement) {
+	defer close(announcements)
+
+	go func() {
+		<-ctx.Done()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	packets := make(chan []byte)
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn net.PacketConn) {
+			defer wg.Done()
+
+			buffer := make([]byte, 2048)
+			for {
+				n, _, err := conn.ReadFrom(buffer)
+				if err != nil {
+					if self.Debug && ctx.Err() == nil {
+						log.Printf("stopped listening => %s\n", err)
+					}
+					return
+				}
+
+				select {
+				case packets <- append([]byte(nil), buffer[:n]...):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(conn)
+	}
+	go func() {
+		wg.Wait()
+		close(packets)
+	}()
+
+	seen := map[string]time.Time{}
+	for data := range packets {
+		if ctx.Err() != nil {
+			continue
+		}
+
+		now := time.Now()
+		key := string(data)
+		if last, ok := seen[key]; ok && now.Sub(last) < duplicateWindow {
+			continue
+		}
+		seen[key] = now
+		if len(seen) > 256 {
+			for key, last := range seen {
+				if now.Sub(last) >= duplicateWindow {
+					delete(seen, key)
+				}
+			}
+		}
+
+		announcement, ok := parseAnnouncement(data)
+		if !ok {
+			continue
+		}
+
+		select {
+		case announcements <- announcement:
+		case <-ctx.Done():
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"net"
+	"testing"
+	"time"
+)
+
+const aliveNotify = "NOTIFY * HTTP/1.1\r\n" +
+	"HOST: 239.255.255.250:1900\r\n" +
+	"CACHE-CONTROL: max-age=1800\r\n" +
+	"LOCATION: http://10.0.1.32:49153/setup.xml\r\n" +
+	"NT: urn:Belkin:device:controllee:1\r\n" +
+	"NTS: ssdp:alive\r\n" +
+	"SERVER: Unspecified, UPnP/1.0, Unspecified\r\n" +
+	"USN: uuid:Socket-1_0-221326K0101234::urn:Belkin:device:controllee:1\r\n" +
+	"\r\n"
+
+const byebyeNotify = "NOTIFY * HTTP/1.1\r\n" +
+	"HOST: 239.255.255.250:1900\r\n" +
+	"nt: urn:Belkin:device:controllee:1\r\n" +
+	"nts: ssdp:byebye\r\n" +
+	"usn: uuid:Socket-1_0-221326K0101234::urn:Belkin:device:controllee:1\r\n" +
+	"\r\n"
+
+const otherNotify = "NOTIFY * HTTP/1.1\r\n" +
+	"HOST: 239.255.255.250:1900\r\n" +
+	"LOCATION: http://10.0.1.1:5000/rootDesc.xml\r\n" +
+	"NT: upnp:rootdevice\r\n" +
+	"NTS: ssdp:alive\r\n" +
+	"USN: uuid:router::upnp:rootdevice\r\n" +
+	"\r\n"
+
+const setupXMLNotify = "NOTIFY * HTTP/1.1\r\n" +
+	"HOST: 239.255.255.250:1900\r\n" +
+	"LOCATION: http://10.0.1.40:49152/setup.xml\r\n" +
+	"NT: urn:schemas-upnp-org:device:MediaRenderer:1\r\n" +
+	"NTS: ssdp:alive\r\n" +
+	"USN: uuid:speaker::urn:schemas-upnp-org:device:MediaRenderer:1\r\n" +
+	"\r\n"
+
+func TestParseAnnouncement(t *testing.T) {
+	Convey("Given an ssdp:alive", t, func() {
+		announcement, ok := parseAnnouncement([]byte(aliveNotify))
+
+		Convey("Then I expect the headers to be parsed", func() {
+			So(ok, ShouldBeTrue)
+			So(announcement.Type, ShouldEqual, Alive)
+			So(announcement.Host, ShouldEqual, "10.0.1.32:49153")
+			So(announcement.USN, ShouldEqual, "uuid:Socket-1_0-221326K0101234::urn:Belkin:device:controllee:1")
+			So(announcement.MaxAge, ShouldEqual, 30*time.Minute)
+		})
+	})
+
+	Convey("Given an ssdp:byebye with lower case headers", t, func() {
+		announcement, ok := parseAnnouncement([]byte(byebyeNotify))
+
+		Convey("Then I expect it to be recognized without a location", func() {
+			So(ok, ShouldBeTrue)
+			So(announcement.Type, ShouldEqual, ByeBye)
+			So(announcement.Host, ShouldEqual, "")
+		})
+	})
+
+	Convey("Given an announcement from another vendor", t, func() {
+		_, ok := parseAnnouncement([]byte(otherNotify))
+
+		Convey("Then I expect it to be ignored", func() {
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given an announcement from another vendor that also serves /setup.xml", t, func() {
+		_, ok := parseAnnouncement([]byte(setupXMLNotify))
+
+		Convey("Then I expect it to be ignored", func() {
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestReadAnnouncements(t *testing.T) {
+	Convey("Given a socket receiving announcements", t, func() {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		announcements := make(chan Announcement)
+		go (&Wemo{}).readAnnouncements(ctx, []net.PacketConn{conn}, announcements)
+
+		sender, _ := net.Dial("udp4", conn.LocalAddr().String())
+		defer sender.Close()
+		sender.Write([]byte(otherNotify))
+		sender.Write([]byte("garbage"))
+		sender.Write([]byte(aliveNotify))
+
+		Convey("Then I expect only the Belkin announcement", func() {
+			select {
+			case announcement := <-announcements:
+				So(announcement.Host, ShouldEqual, "10.0.1.32:49153")
+			case <-time.After(2 * time.Second):
+				So("timed out", ShouldBeEmpty)
+			}
+
+			Convey("And I expect the channel to close when cancelled", func() {
+				cancel()
+				_, ok := <-announcements
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestReadAnnouncementsFromSeveralInterfaces(t *testing.T) {
+	Convey("Given the same announcement arriving on two sockets", t, func() {
+		first, _ := net.ListenPacket("udp4", "127.0.0.1:0")
+		second, _ := net.ListenPacket("udp4", "127.0.0.1:0")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		announcements := make(chan Announcement, 4)
+		go (&Wemo{}).readAnnouncements(ctx, []net.PacketConn{first, second}, announcements)
+
+		for _, conn := range []net.PacketConn{first, second} {
+			sender, _ := net.Dial("udp4", conn.LocalAddr().String())
+			sender.Write([]byte(aliveNotify))
+			sender.Close()
+		}
+
+		Convey("Then I expect it to be reported once", func() {
+			select {
+			case announcement := <-announcements:
+				So(announcement.Type, ShouldEqual, Alive)
+			case <-time.After(2 * time.Second):
+				So("timed out", ShouldBeEmpty)
+			}
+
+			select {
+			case <-announcements:
+				So("duplicate announcement", ShouldBeEmpty)
+			case <-time.After(300 * time.Millisecond):
+			}
+		})
+	})
+}
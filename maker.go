@@ -0,0 +1,131 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"strconv"
+)
+
+const MakerURN = "urn:Belkin:device:Maker:1"
+
+type SwitchMode int
+
+const (
+	// SwitchModeToggle leaves the relay in whatever state it was last set to
+	SwitchModeToggle SwitchMode = 0
+	// SwitchModeMomentary closes the relay for about a second and then opens it again
+	SwitchModeMomentary SwitchMode = 1
+)
+
+func (m SwitchMode) String() string {
+	switch m {
+	case SwitchModeToggle:
+		return "toggle"
+	case SwitchModeMomentary:
+		return "momentary"
+	default:
+		return fmt.Sprintf("SwitchMode(%d)", int(m))
+	}
+}
+
+type MakerAttributes struct {
+	Switch        bool       `json:"switch"`
+	Sensor        bool       `json:"sensor"`
+	SwitchMode    SwitchMode `json:"switch-mode"`
+	SensorPresent bool       `json:"sensor-present"`
+}
+
+// Maker is a relay with an optional sensor input
+type Maker struct {
+	*Device
+}
+
+func parseMakerAttributes(attributes map[string]string) *MakerAttributes {
+	return &MakerAttributes{
+		Switch:        attributes["Switch"] == "1",
+		Sensor:        attributes["Sensor"] == "1",
+		SwitchMode:    SwitchMode(attributeInt(attributes, "SwitchMode")),
+		SensorPresent: attributes["SensorPresent"] == "1",
+	}
+}
+
+func (m *Maker) Attributes(ctx context.Context) (*MakerAttributes, error) {
+	attributes, err := m.GetAttributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMakerAttributes(attributes), nil
+}
+
+// Relay returns true when the relay is closed
+func (m *Maker) Relay(ctx context.Context) (bool, error) {
+	attributes, err := m.Attributes(ctx)
+	if err != nil {
+		return false, err
+	}
+	return attributes.Switch, nil
+}
+
+// SetRelay closes (true) or opens (false) the relay.  In momentary mode the
+// device opens the relay again on its own
+func (m *Maker) SetRelay(ctx context.Context, closed bool) error {
+	value := "0"
+	if closed {
+		value = "1"
+	}
+
+	_, err := m.Call(ctx, ServiceBasicEvent, "SetBinaryState", map[string]string{"BinaryState": value})
+	return err
+}
+
+// Sensor returns the state of the sensor input; ok is false when no sensor is attached
+func (m *Maker) Sensor(ctx context.Context) (value bool, ok bool, err error) {
+	attributes, err := m.Attributes(ctx)
+	if err != nil {
+		return false, false, err
+	}
+	return attributes.Sensor, attributes.SensorPresent, nil
+}
+
+func (m *Maker) SwitchMode(ctx context.Context) (SwitchMode, error) {
+	attributes, err := m.Attributes(ctx)
+	if err != nil {
+		return SwitchModeToggle, err
+	}
+	return attributes.SwitchMode, nil
+}
+
+func (m *Maker) SetSwitchMode(ctx context.Context, mode SwitchMode) error {
+	return m.SetAttributes(ctx, map[string]string{
+		"SwitchMode": strconv.Itoa(int(mode)),
+	})
+}
+
+// Sensor returns the sensor state carried by a Maker attributeList event.  ok
+// is false when the event didn't include a sensor change
+func (e Event) Sensor() (value bool, ok bool) {
+	attributes, ok := e.Attributes()
+	if !ok {
+		return false, false
+	}
+
+	sensor, ok := attributes["Sensor"]
+	if !ok {
+		return false, false
+	}
+	return sensor == "1", true
+}
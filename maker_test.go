@@ -0,0 +1,86 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+func TestMaker(t *testing.T) {
+	Convey("Given a Maker", t, func() {
+		attributes := `<attribute><name>Switch</name><value>0</value></attribute><attribute><name>Sensor</name><value>1</value></attribute><attribute><name>SwitchMode</name><value>1</value></attribute><attribute><name>SensorPresent</name><value>1</value></attribute>`
+		var received string
+		fake := &fakeDevice{
+			DeviceType: MakerURN,
+			Services:   []string{ServiceBasicEvent, ServiceDeviceEvent},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				switch action {
+				case "GetAttributes":
+					return map[string]string{"attributeList": attributes}, nil
+				case "SetAttributes":
+					received = args["attributeList"]
+				}
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		maker := &Maker{Device: device}
+		ctx := context.Background()
+
+		Convey("When I call #Attributes", func() {
+			attrs, err := maker.Attributes(ctx)
+
+			Convey("Then I expect the attributes to be parsed", func() {
+				So(err, ShouldBeNil)
+				So(attrs.Switch, ShouldBeFalse)
+				So(attrs.Sensor, ShouldBeTrue)
+				So(attrs.SwitchMode, ShouldEqual, SwitchModeMomentary)
+				So(attrs.SensorPresent, ShouldBeTrue)
+			})
+		})
+
+		Convey("When I call #SetSwitchMode", func() {
+			err := maker.SetSwitchMode(ctx, SwitchModeToggle)
+
+			Convey("Then I expect the attribute list to be sent", func() {
+				So(err, ShouldBeNil)
+				So(received, ShouldEqual, "<attribute><name>SwitchMode</name><value>0</value></attribute>")
+			})
+		})
+	})
+
+	Convey("Given a SetAttributes envelope", t, func() {
+		args := map[string]string{"attributeList": formatAttributeList(map[string]string{"Switch": "1"})}
+
+		Convey("Then I expect the attribute list to be escaped", func() {
+			data, _ := newEnvelope(ServiceDeviceEvent, "SetAttributes", args)
+			So(strings.Contains(string(data), "<attributeList>&lt;attribute&gt;&lt;name&gt;Switch&lt;/name&gt;"), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a sensor event", t, func() {
+		event := Event{Name: "attributeList", Value: "<attribute><name>Sensor</name><value>0</value></attribute>"}
+
+		Convey("Then I expect the sensor state", func() {
+			sensor, ok := event.Sensor()
+			So(ok, ShouldBeTrue)
+			So(sensor, ShouldBeFalse)
+		})
+	})
+}
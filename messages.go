@@ -14,53 +14,217 @@
 package wemo
 
 import (
-	"bufio"
 	"bytes"
+	"code.google.com/p/go.net/context"
+	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"time"
+	"sort"
 )
 
-func post(hostAndPort, action, body string) (*http.Response, error) {
-	tcpConn, err := timeoutDialer(2*time.Second, 2*time.Second)("tcp", hostAndPort)
+const (
+	soapEnvelopeNS  = "http://schemas.xmlsoap.org/soap/envelope/"
+	soapEncodingNS  = "http://schemas.xmlsoap.org/soap/encoding/"
+	xmlHeader       = `<?xml version="1.0" encoding="utf-8"?>` + "\n"
+	soapContentType = `text/xml; charset="utf-8"`
+)
+
+// SOAPError is returned when a device answers an action with a SOAP fault
+type SOAPError struct {
+	Action      string
+	Code        int
+	Description string
+}
+
+func (e *SOAPError) Error() string {
+	return fmt.Sprintf("%s failed with UPnP error %d => %s", e.Action, e.Code, e.Description)
+}
+
+// Response holds the arguments a device returned for an action
+type Response struct {
+	Action string
+	Args   map[string]string
+	data   []byte
+}
+
+// Unmarshal decodes the response element into v using encoding/xml
+func (r *Response) Unmarshal(v interface{}) error {
+	return xml.Unmarshal(r.data, v)
+}
+
+type envelope struct {
+	XMLName       xml.Name `xml:"s:Envelope"`
+	XMLNS         string   `xml:"xmlns:s,attr"`
+	EncodingStyle string   `xml:"s:encodingStyle,attr"`
+	Body          struct {
+		Action action
+	} `xml:"s:Body"`
+}
+
+// action is the body of the SOAP request.  args may be nil, a map[string]string
+// or a struct whose fields are encoded, in order, as the action arguments
+type action struct {
+	ServiceType string
+	Name        string
+	Args        interface{}
+}
+
+func (a action) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{
+		Name: xml.Name{Local: "u:" + a.Name},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns:u"}, Value: a.ServiceType}},
+	}
+
+	switch args := a.Args.(type) {
+	case nil:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+
+	case map[string]string:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+
+		var keys []string
+		for key := range args {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if err := e.EncodeElement(args[key], xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+
+	default:
+		return e.EncodeElement(args, start)
+	}
+}
+
+func newEnvelope(serviceType, name string, args interface{}) ([]byte, error) {
+	env := envelope{
+		XMLNS:         soapEnvelopeNS,
+		EncodingStyle: soapEncodingNS,
+	}
+	env.Body.Action = action{ServiceType: serviceType, Name: name, Args: args}
+
+	data, err := xml.Marshal(env)
 	if err != nil {
 		return nil, err
 	}
-	defer tcpConn.Close()
 
-	preamble := fmt.Sprintf("POST http://%v/upnp/control/basicevent1 HTTP/1.1\r\nContent-type: text/xml; charset=\"utf-8\"\r\nSOAPACTION: \"urn:Belkin:service:basicevent:1#%s\"\r\nContent-Length: %v\r\n\r\n", hostAndPort, action, len(body))
-	tcpConn.Write([]byte(preamble + body))
+	return append([]byte(xmlHeader), data...), nil
+}
 
-	data, err := ioutil.ReadAll(tcpConn)
-	if err != nil {
+func unmarshalResponse(name string, data []byte) (*Response, error) {
+	env := struct {
+		Body struct {
+			Fault *struct {
+				Code        int    `xml:"detail>UPnPError>errorCode"`
+				Description string `xml:"detail>UPnPError>errorDescription"`
+			} `xml:"Fault"`
+			Content []byte `xml:",innerxml"`
+		} `xml:"Body"`
+	}{}
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+
+	if fault := env.Body.Fault; fault != nil {
+		return nil, &SOAPError{Action: name, Code: fault.Code, Description: fault.Description}
+	}
+
+	content := struct {
+		XMLName xml.Name
+		Args    []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	}{}
+	if err := xml.Unmarshal(env.Body.Content, &content); err != nil {
 		return nil, err
 	}
 
-	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	response := &Response{
+		Action: content.XMLName.Local,
+		Args:   map[string]string{},
+		data:   env.Body.Content,
+	}
+	for _, arg := range content.Args {
+		response.Args[arg.XMLName.Local] = arg.Value
+	}
+
+	return response, nil
 }
 
-func newGetBinaryStateMessage() string {
-	return `<?xml version="1.0" encoding="utf-8"?>
-<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
-  <s:Body>
-    <u:GetBinaryState xmlns:u="urn:Belkin:service:basicevent:1"></u:GetBinaryState>
-  </s:Body>
-</s:Envelope>`
+// do executes the request, abandoning it if the context is cancelled first
+func do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	type result struct {
+		response *http.Response
+		err      error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		response, err := client.Do(req)
+		ch <- result{response, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		if transport, ok := client.Transport.(*http.Transport); ok {
+			transport.CancelRequest(req)
+		}
+		if r := <-ch; r.err == nil {
+			r.response.Body.Close()
+		}
+		return nil, ctx.Err()
+	case r := <-ch:
+		return r.response, r.err
+	}
 }
 
-func newSetBinaryStateMessage(on bool) string {
-	value := 0
-	if on {
-		value = 1
+func post(ctx context.Context, uri, serviceType, name string, args interface{}) (*Response, error) {
+	body, err := newEnvelope(serviceType, name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", uri, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Close = true
+	req.Header.Set("Content-Type", soapContentType)
+	req.Header.Set("SOAPACTION", fmt.Sprintf(`"%s#%s"`, serviceType, name))
+
+	response, err := do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
-<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
-  <s:Body>
-    <u:SetBinaryState xmlns:u="urn:Belkin:service:basicevent:1">
-      <BinaryState>%v</BinaryState>
-    </u:SetBinaryState>
-  </s:Body>
-</s:Envelope>`, value)
+	// faults are reported with a 500, so try to decode those as well
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusInternalServerError {
+		return nil, fmt.Errorf("%s returned status code => %d", name, response.StatusCode)
+	}
+
+	result, err := unmarshalResponse(name, data)
+	if response.StatusCode != http.StatusOK {
+		if _, ok := err.(*SOAPError); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s returned status code => %d", name, response.StatusCode)
+	}
+	return result, err
 }
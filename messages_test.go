@@ -0,0 +1,252 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"encoding/xml"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSetupXML = `<?xml version="1.0"?>
+<root xmlns="urn:Belkin:device-1-0">
+  <device>
+    <deviceType>urn:Belkin:device:controllee:1</deviceType>
+    <friendlyName>Test Switch</friendlyName>
+    <serviceList>
+      <service>
+        <serviceType>urn:Belkin:service:basicevent:1</serviceType>
+        <serviceId>urn:Belkin:serviceId:basicevent1</serviceId>
+        <controlURL>/upnp/control/basicevent1</controlURL>
+        <eventSubURL>/upnp/event/basicevent1</eventSubURL>
+        <SCPDURL>/eventservice.xml</SCPDURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`
+
+func TestNewEnvelope(t *testing.T) {
+	Convey("Given a SetBinaryState action", t, func() {
+		args := map[string]string{"BinaryState": "1"}
+
+		Convey("When I call #newEnvelope", func() {
+			data, err := newEnvelope(ServiceBasicEvent, "SetBinaryState", args)
+
+			Convey("Then I expect no errors", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then I expect a SOAP envelope", func() {
+				So(string(data), ShouldContainSubstring, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"`)
+			})
+
+			Convey("Then I expect the action to be namespaced by service type", func() {
+				So(string(data), ShouldContainSubstring, `<u:SetBinaryState xmlns:u="urn:Belkin:service:basicevent:1"><BinaryState>1</BinaryState></u:SetBinaryState>`)
+			})
+		})
+	})
+
+	Convey("Given struct arguments", t, func() {
+		args := struct {
+			UTC      int
+			TimeZone string
+		}{1400000000, "-5.0"}
+
+		Convey("When I call #newEnvelope", func() {
+			data, _ := newEnvelope(ServiceTimeSync, "TimeSync", args)
+
+			Convey("Then I expect the fields to be encoded in order", func() {
+				So(string(data), ShouldContainSubstring, `<u:TimeSync xmlns:u="urn:Belkin:service:timesync:1"><UTC>1400000000</UTC><TimeZone>-5.0</TimeZone></u:TimeSync>`)
+			})
+		})
+	})
+}
+
+func TestUnmarshalResponse(t *testing.T) {
+	Convey("Given a GetBinaryState response", t, func() {
+		data := []byte(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>
+<u:GetBinaryStateResponse xmlns:u="urn:Belkin:service:basicevent:1">
+<BinaryState>1</BinaryState>
+</u:GetBinaryStateResponse>
+</s:Body> </s:Envelope>`)
+
+		Convey("When I call #unmarshalResponse", func() {
+			response, err := unmarshalResponse("GetBinaryState", data)
+
+			Convey("Then I expect no errors", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then I expect the response action", func() {
+				So(response.Action, ShouldEqual, "GetBinaryStateResponse")
+			})
+
+			Convey("Then I expect the response arguments", func() {
+				So(response.Args["BinaryState"], ShouldEqual, "1")
+			})
+
+			Convey("Then I expect to unmarshal into a struct", func() {
+				v := struct {
+					BinaryState int
+				}{}
+				So(response.Unmarshal(&v), ShouldBeNil)
+				So(v.BinaryState, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a SOAP fault", t, func() {
+		data := []byte(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>
+<s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring>
+<detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>-1</errorCode><errorDescription>Invalid Action</errorDescription></UPnPError></detail>
+</s:Fault></s:Body></s:Envelope>`)
+
+		Convey("When I call #unmarshalResponse", func() {
+			_, err := unmarshalResponse("Bogus", data)
+
+			Convey("Then I expect a SOAPError", func() {
+				soapErr, ok := err.(*SOAPError)
+				So(ok, ShouldBeTrue)
+				So(soapErr.Code, ShouldEqual, -1)
+				So(soapErr.Description, ShouldEqual, "Invalid Action")
+			})
+		})
+	})
+}
+
+func TestCall(t *testing.T) {
+	Convey("Given a device", t, func() {
+		var soapAction, body string
+		status := http.StatusOK
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/setup.xml":
+				w.Write([]byte(testSetupXML))
+			case "/upnp/control/basicevent1":
+				data, _ := ioutil.ReadAll(req.Body)
+				soapAction, body = req.Header.Get("SOAPACTION"), string(data)
+				w.WriteHeader(status)
+				w.Write([]byte(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetBinaryStateResponse xmlns:u="urn:Belkin:service:basicevent:1"><BinaryState>1</BinaryState></u:GetBinaryStateResponse></s:Body></s:Envelope>`))
+			default:
+				http.NotFound(w, req)
+			}
+		}))
+		defer server.Close()
+
+		device := &Device{Host: strings.TrimPrefix(server.URL, "http://")}
+
+		Convey("When I call a supported action", func() {
+			response, err := device.Call(context.Background(), ServiceBasicEvent, "GetBinaryState", nil)
+
+			Convey("Then I expect the control url from setup.xml to be used", func() {
+				So(err, ShouldBeNil)
+				So(soapAction, ShouldEqual, `"urn:Belkin:service:basicevent:1#GetBinaryState"`)
+				So(body, ShouldContainSubstring, "<u:GetBinaryState")
+				So(response.Args["BinaryState"], ShouldEqual, "1")
+			})
+		})
+
+		Convey("When the device returns a 500 without a fault", func() {
+			status = http.StatusInternalServerError
+			response, err := device.Call(context.Background(), ServiceBasicEvent, "GetBinaryState", nil)
+
+			Convey("Then I expect an error", func() {
+				So(err, ShouldNotBeNil)
+				So(response, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call an unsupported service", func() {
+			_, err := device.Call(context.Background(), ServiceRules, "FetchRules", nil)
+
+			Convey("Then I expect an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+// fakeDevice answers SOAP actions for every service listed in its setup.xml
+type fakeDevice struct {
+	DeviceType   string
+	UDN          string
+	MacAddress   string
+	SerialNumber string
+	Services     []string
+	Handler      func(serviceType, action string, args map[string]string) (map[string]string, error)
+}
+
+func (f *fakeDevice) controlURL(serviceType string) string {
+	// urn:Belkin:service:basicevent:1 => /upnp/control/basicevent1
+	parts := strings.Split(serviceType, ":")
+	return "/upnp/control/" + parts[3] + parts[4]
+}
+
+func (f *fakeDevice) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/setup.xml" {
+		fmt.Fprintf(w, `<?xml version="1.0"?><root xmlns="urn:Belkin:device-1-0"><device><deviceType>%s</deviceType><UDN>%s</UDN><macAddress>%s</macAddress><serialNumber>%s</serialNumber><serviceList>`,
+			f.DeviceType, f.UDN, f.MacAddress, f.SerialNumber)
+		for _, serviceType := range f.Services {
+			fmt.Fprintf(w, `<service><serviceType>%s</serviceType><controlURL>%s</controlURL></service>`, serviceType, f.controlURL(serviceType))
+		}
+		fmt.Fprint(w, `</serviceList></device></root>`)
+		return
+	}
+
+	for _, serviceType := range f.Services {
+		if req.URL.Path != f.controlURL(serviceType) {
+			continue
+		}
+
+		// requests share the shape of responses: Body > action > arguments
+		data, _ := ioutil.ReadAll(req.Body)
+		request, err := unmarshalResponse("", data)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		action := request.Action
+		args := request.Args
+		result, err := f.Handler(serviceType, action, args)
+		if soapErr, ok := err.(*SOAPError); ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault><detail><UPnPError><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError></detail></s:Fault></s:Body></s:Envelope>`, soapErr.Code, soapErr.Description)
+			return
+		}
+
+		fmt.Fprintf(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:%sResponse xmlns:u="%s">`, action, serviceType)
+		for key, value := range result {
+			fmt.Fprintf(w, "<%s>", key)
+			xml.EscapeText(w, []byte(value))
+			fmt.Fprintf(w, "</%s>", key)
+		}
+		fmt.Fprintf(w, `</u:%sResponse></s:Body></s:Envelope>`, action)
+		return
+	}
+
+	http.NotFound(w, req)
+}
+
+// start serves the fake device and returns a Device pointed at it
+func (f *fakeDevice) start() (*Device, func()) {
+	server := httptest.NewServer(f)
+	return &Device{Host: strings.TrimPrefix(server.URL, "http://")}, server.Close
+}
@@ -0,0 +1,203 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeviceDetails combines the metainfo and deviceinfo services.  Unlike the
+// device type, the SKU and product name tell apart models that share a URN
+// e.g. the Switch, Mini and Outdoor Plug are all controllees
+type DeviceDetails struct {
+	MacAddr          string `json:"mac-addr"`
+	SerialNumber     string `json:"serial-number"`
+	SKU              string `json:"sku"`
+	FirmwareVersion  string `json:"firmware-version"`
+	Region           string `json:"region"`
+	HardwareRevision string `json:"hardware-revision,omitempty"`
+	SetupSSID        string `json:"setup-ssid"`
+	ProductName      string `json:"product-name"`
+
+	// from GetExtMetaInfo
+	Uptime       time.Duration `json:"uptime"`
+	RemoteAccess bool          `json:"remote-access"`
+
+	// BinaryState and Brightness are -1 when the device doesn't report them
+	BinaryState int `json:"binary-state"`
+	Brightness  int `json:"brightness"`
+}
+
+// parseMetaInfo parses GetMetaInfo e.g.
+// EC1A59F1C2D4|221326K0101234|Plugin Device|WeMo_WW_2.00.11057.PVT-OWRT-SNS|WeMo.Switch.D4C|Socket
+func parseMetaInfo(details *DeviceDetails, value string) {
+	fields := strings.Split(value, "|")
+	set := func(i int, target *string) {
+		if i < len(fields) {
+			*target = strings.TrimSpace(fields[i])
+		}
+	}
+
+	set(0, &details.MacAddr)
+	set(1, &details.SerialNumber)
+	set(2, &details.SKU)
+	set(3, &details.FirmwareVersion)
+	set(4, &details.SetupSSID)
+	set(5, &details.ProductName)
+	details.Region = firmwareRegion(details.FirmwareVersion)
+}
+
+// parseExtMetaInfo parses GetExtMetaInfo e.g.
+// 1|0|1|0|1:15:32|4|1418681720|123456|1|Socket
+//
+// the fields are client state, ice running, nat initialized, last auth,
+// uptime (h:m:s), firmware update state, utc time, home id, remote access
+// enabled and product name
+func parseExtMetaInfo(details *DeviceDetails, value string) {
+	fields := strings.Split(value, "|")
+	if len(fields) > 4 {
+		details.Uptime = parseUptime(fields[4])
+	}
+	if len(fields) > 8 {
+		details.RemoteAccess = strings.TrimSpace(fields[8]) == "1"
+	}
+	if len(fields) > 9 && details.ProductName == "" {
+		details.ProductName = strings.TrimSpace(fields[9])
+	}
+}
+
+func parseUptime(value string) time.Duration {
+	var uptime time.Duration
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != len(units) {
+		return 0
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		uptime += time.Duration(n) * units[i]
+	}
+	return uptime
+}
+
+// firmwareRegion returns the region code of a firmware version e.g. WW for
+// WeMo_WW_2.00.11057.PVT-OWRT-SNS
+func firmwareRegion(version string) string {
+	parts := strings.Split(version, "_")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
+
+// parseInformation parses GetInformation, which returns an escaped xml
+// document describing the device
+func parseInformation(details *DeviceDetails, value string) error {
+	var information struct {
+		FirmwareVersion string `xml:"DeviceInformation>firmwareVersion"`
+		HardwareVersion string `xml:"DeviceInformation>hwVersion"`
+		MacAddress      string `xml:"DeviceInformation>macAddress"`
+		ProductName     string `xml:"DeviceInformation>productName"`
+		BinaryState     string `xml:"DeviceInformation>binaryState"`
+		Brightness      string `xml:"DeviceInformation>brightness"`
+	}
+	if err := xml.Unmarshal([]byte(value), &information); err != nil {
+		return err
+	}
+
+	if information.HardwareVersion != "" {
+		details.HardwareRevision = information.HardwareVersion
+	}
+	if details.FirmwareVersion == "" {
+		details.FirmwareVersion = information.FirmwareVersion
+		details.Region = firmwareRegion(information.FirmwareVersion)
+	}
+	if details.MacAddr == "" {
+		details.MacAddr = information.MacAddress
+	}
+	if details.ProductName == "" {
+		details.ProductName = information.ProductName
+	}
+	if state, err := parseBinaryState(information.BinaryState); err == nil {
+		details.BinaryState = state
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(information.Brightness)); err == nil {
+		details.Brightness = n
+	}
+	return nil
+}
+
+// parseDeviceInformation parses GetDeviceInformation e.g.
+// EC1A59F1C2D4|WeMo_WW_2.00.11057.PVT-OWRT-SNS|0|1|0|1418681720
+func parseDeviceInformation(details *DeviceDetails, value string) {
+	fields := strings.Split(value, "|")
+	if len(fields) > 1 && details.FirmwareVersion == "" {
+		details.FirmwareVersion = strings.TrimSpace(fields[1])
+		details.Region = firmwareRegion(details.FirmwareVersion)
+	}
+	if len(fields) > 3 {
+		if state, err := parseBinaryState(fields[3]); err == nil {
+			details.BinaryState = state
+		}
+	}
+}
+
+// FetchDeviceDetails queries whichever of the metainfo and deviceinfo
+// services the device supports
+func (d *Device) FetchDeviceDetails(ctx context.Context) (*DeviceDetails, error) {
+	details := &DeviceDetails{
+		BinaryState: -1,
+		Brightness:  -1,
+	}
+
+	if _, err := d.service(ctx, ServiceMetaInfo); err == nil {
+		response, err := d.Call(ctx, ServiceMetaInfo, "GetMetaInfo", nil)
+		if err != nil {
+			return nil, err
+		}
+		parseMetaInfo(details, response.Args["MetaInfo"])
+
+		response, err = d.Call(ctx, ServiceMetaInfo, "GetExtMetaInfo", nil)
+		if err != nil {
+			return nil, err
+		}
+		parseExtMetaInfo(details, response.Args["ExtMetaInfo"])
+	}
+
+	if _, err := d.service(ctx, ServiceDeviceInfo); err == nil {
+		response, err := d.Call(ctx, ServiceDeviceInfo, "GetInformation", nil)
+		if err == nil {
+			if err := parseInformation(details, response.Args["Information"]); err != nil {
+				return nil, err
+			}
+			return details, nil
+		}
+
+		// older firmware only implements GetDeviceInformation
+		response, err = d.Call(ctx, ServiceDeviceInfo, "GetDeviceInformation", nil)
+		if err != nil {
+			return nil, err
+		}
+		parseDeviceInformation(details, response.Args["DeviceInformation"])
+	}
+
+	return details, nil
+}
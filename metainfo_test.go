@@ -0,0 +1,88 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestDeviceDetails(t *testing.T) {
+	Convey("Given a device with metainfo and deviceinfo", t, func() {
+		fake := &fakeDevice{
+			DeviceType: "urn:Belkin:device:dimmer:1",
+			Services:   []string{ServiceMetaInfo, ServiceDeviceInfo},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				switch action {
+				case "GetMetaInfo":
+					return map[string]string{"MetaInfo": "EC1A59F1C2D4|221326K0101234|F7C059|WeMo_US_2.00.11408.PVT-OWRT-Dimmer|WeMo.Dimmer.D4C|Dimmer"}, nil
+				case "GetExtMetaInfo":
+					return map[string]string{"ExtMetaInfo": "1|0|1|0|26:15:32|4|1418681720|123456|1|Dimmer"}, nil
+				case "GetInformation":
+					return map[string]string{"Information": "<Device><DeviceInformation><firmwareVersion>WeMo_US_2.00.11408.PVT-OWRT-Dimmer</firmwareVersion><hwVersion>v2</hwVersion><binaryState>1</binaryState><brightness>42</brightness></DeviceInformation></Device>"}, nil
+				}
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		Convey("When I call #FetchDeviceDetails", func() {
+			details, err := device.FetchDeviceDetails(context.Background())
+
+			Convey("Then I expect the combined details", func() {
+				So(err, ShouldBeNil)
+				So(details.MacAddr, ShouldEqual, "EC1A59F1C2D4")
+				So(details.SerialNumber, ShouldEqual, "221326K0101234")
+				So(details.SKU, ShouldEqual, "F7C059")
+				So(details.Region, ShouldEqual, "US")
+				So(details.HardwareRevision, ShouldEqual, "v2")
+				So(details.ProductName, ShouldEqual, "Dimmer")
+				So(details.Uptime, ShouldEqual, 26*time.Hour+15*time.Minute+32*time.Second)
+				So(details.RemoteAccess, ShouldBeTrue)
+				So(details.BinaryState, ShouldEqual, 1)
+				So(details.Brightness, ShouldEqual, 42)
+			})
+		})
+	})
+
+	Convey("Given a device that only implements GetDeviceInformation", t, func() {
+		fake := &fakeDevice{
+			DeviceType: "urn:Belkin:device:controllee:1",
+			Services:   []string{ServiceDeviceInfo},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				if action == "GetDeviceInformation" {
+					return map[string]string{"DeviceInformation": "EC1A59F1C2D4|WeMo_WW_2.00.11057.PVT-OWRT-SNS|0|1|0|1418681720"}, nil
+				}
+				return nil, &SOAPError{Code: 401, Description: "Invalid Action"}
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		Convey("When I call #FetchDeviceDetails", func() {
+			details, err := device.FetchDeviceDetails(context.Background())
+
+			Convey("Then I expect to fall back to the pipe delimited form", func() {
+				So(err, ShouldBeNil)
+				So(details.FirmwareVersion, ShouldEqual, "WeMo_WW_2.00.11057.PVT-OWRT-SNS")
+				So(details.Region, ShouldEqual, "WW")
+				So(details.BinaryState, ShouldEqual, 1)
+				So(details.Brightness, ShouldEqual, -1)
+			})
+		})
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"time"
+)
+
+const SensorURN = "urn:Belkin:device:sensor:1"
+
+// polling bounds used when no callback address is available for events
+const (
+	DefaultMinPollInterval = 500 * time.Millisecond
+	DefaultMaxPollInterval = 5 * time.Second
+)
+
+type MotionEvent struct {
+	Motion bool
+	Time   time.Time
+}
+
+// MotionSensor reports BinaryState 1 while it detects motion
+type MotionSensor struct {
+	*Device
+
+	// polling starts at MinPollInterval after a change and backs off to
+	// MaxPollInterval while nothing happens
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+}
+
+func (m *MotionSensor) Motion(ctx context.Context) (bool, error) {
+	response, err := m.Call(ctx, ServiceBasicEvent, "GetBinaryState", nil)
+	if err != nil {
+		return false, err
+	}
+
+	state, err := parseBinaryState(response.Args["BinaryState"])
+	if err != nil {
+		return false, fmt.Errorf("unable to parse BinaryState => %s", response.Args["BinaryState"])
+	}
+	return state == 1, nil
+}
+
+// Watch delivers a MotionEvent with the current state and then one for every
+// change until ctx is done, at which point the channel is closed.  When
+// callbackAddr is set, e.g. 10.0.1.5, changes arrive as GENA events; otherwise,
+// or if the subscription fails, the sensor is polled
+func (m *MotionSensor) Watch(ctx context.Context, callbackAddr string) (<-chan MotionEvent, error) {
+	motion, err := m.Motion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan MotionEvent, 16)
+	events <- MotionEvent{Motion: motion, Time: time.Now()}
+
+	if callbackAddr != "" {
+		subscriber, err := NewSubscriber(callbackAddr)
+		if err == nil {
+			if _, err = subscriber.Subscribe(ctx, m.Device, ServiceBasicEvent); err == nil {
+				go m.watchEvents(ctx, subscriber, motion, events)
+				return events, nil
+			}
+			subscriber.Close()
+		}
+		m.printf("unable to subscribe to %s, polling instead => %s\n", m.CurrentHost(), err)
+	}
+
+	go m.poll(ctx, motion, events)
+	return events, nil
+}
+
+// watchEvents relays GENA events until ctx is done; should the subscriber go
+// away first, it falls back to polling so the caller keeps getting events
+func (m *MotionSensor) watchEvents(ctx context.Context, subscriber *Subscriber, last bool, events chan MotionEvent) {
+	defer subscriber.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			close(events)
+			return
+		case event, ok := <-subscriber.Events():
+			if !ok {
+				m.printf("lost subscription to %s, polling instead\n", m.CurrentHost())
+				m.poll(ctx, last, events)
+				return
+			}
+
+			state, ok := event.BinaryState()
+			if !ok || (state == 1) == last {
+				continue
+			}
+			last = state == 1
+
+			select {
+			case events <- MotionEvent{Motion: last, Time: event.Time}:
+			case <-ctx.Done():
+				close(events)
+				return
+			}
+		}
+	}
+}
+
+func (m *MotionSensor) poll(ctx context.Context, last bool, events chan MotionEvent) {
+	defer close(events)
+
+	minInterval, maxInterval := m.MinPollInterval, m.MaxPollInterval
+	if minInterval <= 0 {
+		minInterval = DefaultMinPollInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = DefaultMaxPollInterval
+	}
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+
+	interval := minInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		motion, err := m.Motion(ctx)
+		if err != nil {
+			m.printf("unable to poll %s => %s\n", m.CurrentHost(), err)
+			interval = maxInterval
+			continue
+		}
+
+		if motion == last {
+			if interval *= 2; interval > maxInterval {
+				interval = maxInterval
+			}
+			continue
+		}
+		last = motion
+		interval = minInterval
+
+		select {
+		case events <- MotionEvent{Motion: motion, Time: time.Now()}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
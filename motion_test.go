@@ -0,0 +1,145 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMotionSensorPolling(t *testing.T) {
+	Convey("Given a motion sensor", t, func() {
+		var mutex sync.Mutex
+		state := "0"
+		fake := &fakeDevice{
+			DeviceType: SensorURN,
+			Services:   []string{ServiceBasicEvent},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return map[string]string{"BinaryState": state}, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		sensor := &MotionSensor{
+			Device:          device,
+			MinPollInterval: 10 * time.Millisecond,
+			MaxPollInterval: 20 * time.Millisecond,
+		}
+
+		Convey("When I watch without a callback address", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			events, err := sensor.Watch(ctx, "")
+			So(err, ShouldBeNil)
+
+			Convey("Then I expect the current state followed by changes", func() {
+				first := <-events
+				So(first.Motion, ShouldBeFalse)
+
+				mutex.Lock()
+				state = "1"
+				mutex.Unlock()
+
+				select {
+				case event := <-events:
+					So(event.Motion, ShouldBeTrue)
+					So(event.Time.IsZero(), ShouldBeFalse)
+				case <-time.After(time.Second):
+					So("timed out waiting for motion", ShouldBeEmpty)
+				}
+
+				cancel()
+				for range events {
+				}
+			})
+		})
+
+		Convey("When the subscriber closes before ctx is done", func() {
+			subscriber, err := NewSubscriber("127.0.0.1")
+			So(err, ShouldBeNil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			events := make(chan MotionEvent, 16)
+			go sensor.watchEvents(ctx, subscriber, false, events)
+			subscriber.Close()
+
+			Convey("Then I expect polling to take over", func() {
+				mutex.Lock()
+				state = "1"
+				mutex.Unlock()
+
+				select {
+				case event, ok := <-events:
+					So(ok, ShouldBeTrue)
+					So(event.Motion, ShouldBeTrue)
+				case <-time.After(time.Second):
+					So("timed out waiting for motion", ShouldBeEmpty)
+				}
+
+				cancel()
+				for range events {
+				}
+			})
+		})
+	})
+
+	Convey("Given a MaxPollInterval below MinPollInterval", t, func() {
+		var mutex sync.Mutex
+		var calls []time.Time
+		fake := &fakeDevice{
+			DeviceType: SensorURN,
+			Services:   []string{ServiceBasicEvent},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				calls = append(calls, time.Now())
+				return map[string]string{"BinaryState": "0"}, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		sensor := &MotionSensor{
+			Device:          device,
+			MinPollInterval: 100 * time.Millisecond,
+			MaxPollInterval: 10 * time.Millisecond,
+		}
+
+		Convey("When I poll", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			events := make(chan MotionEvent, 16)
+			go sensor.poll(ctx, false, events)
+			time.Sleep(350 * time.Millisecond)
+			cancel()
+			for range events {
+			}
+
+			Convey("Then I expect polling to stay at MinPollInterval rather than back off", func() {
+				mutex.Lock()
+				defer mutex.Unlock()
+				So(len(calls), ShouldBeGreaterThanOrEqualTo, 3)
+				for i := 1; i < len(calls); i++ {
+					gap := calls[i].Sub(calls[i-1])
+					So(gap, ShouldBeGreaterThanOrEqualTo, 90*time.Millisecond)
+					So(gap, ShouldBeLessThan, 180*time.Millisecond)
+				}
+			})
+		})
+	})
+}
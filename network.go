@@ -18,6 +18,7 @@ import (
 	"log"
 	"net"
 	"regexp"
+	"strings"
 )
 
 var ipAddrRE = regexp.MustCompile(`^(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3})/\d{1,3}$`)
@@ -52,3 +53,89 @@ func NewByInterface(name string) (*Wemo, error) {
 	// nope, couldn't find one
 	return nil, errors.New("unable to find ip address associated with interface, " + name)
 }
+
+// NewAuto discovers on every interface that is up, multicast capable and not
+// a loopback.  Set Include or Exclude to limit the interfaces used
+func NewAuto() *Wemo {
+	return &Wemo{auto: true}
+}
+
+// matchesAddr reports whether pattern, an interface name or subnet, matches
+// the address ip of iface
+func matchesAddr(pattern string, iface net.Interface, ip net.IP) bool {
+	if !strings.Contains(pattern, "/") {
+		return pattern == iface.Name
+	}
+
+	_, ipNet, err := net.ParseCIDR(pattern)
+	if err != nil {
+		return false
+	}
+	return ipNet.Contains(ip)
+}
+
+func matchesAny(patterns []string, iface net.Interface, ip net.IP) bool {
+	for _, pattern := range patterns {
+		if matchesAddr(pattern, iface, ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectIpAddrs returns, for each usable interface, the first ipv4 address
+// that is included and not excluded
+func selectIpAddrs(ifaces []net.Interface, addrsOf func(net.Interface) []net.IP, include, exclude []string) []string {
+	var ipAddrs []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		for _, ip := range addrsOf(iface) {
+			if len(include) > 0 && !matchesAny(include, iface, ip) {
+				continue
+			}
+			if matchesAny(exclude, iface, ip) {
+				continue
+			}
+
+			ipAddrs = append(ipAddrs, ip.String())
+			break
+		}
+	}
+	return ipAddrs
+}
+
+func ipv4Addrs(iface net.Interface) []net.IP {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			ips = append(ips, ipNet.IP.To4())
+		}
+	}
+	return ips
+}
+
+// ipAddrs returns the local addresses discovery runs on
+func (self *Wemo) ipAddrs() ([]string, error) {
+	if !self.auto {
+		return []string{self.ipAddr}, nil
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	ipAddrs := selectIpAddrs(ifaces, ipv4Addrs, self.Include, self.Exclude)
+	if len(ipAddrs) == 0 {
+		return nil, errors.New("no usable interfaces found for discovery")
+	}
+	return ipAddrs, nil
+}
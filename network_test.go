@@ -0,0 +1,86 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"net"
+	"testing"
+)
+
+func TestSelectIpAddrs(t *testing.T) {
+	Convey("Given several interfaces", t, func() {
+		up := net.FlagUp | net.FlagMulticast
+		ifaces := []net.Interface{
+			{Name: "lo0", Flags: up | net.FlagLoopback},
+			{Name: "en0", Flags: up},
+			{Name: "en1", Flags: up},
+			{Name: "utun0", Flags: net.FlagUp},
+			{Name: "en2", Flags: net.FlagMulticast},
+			{Name: "bridge0", Flags: up},
+		}
+		addrs := map[string][]net.IP{
+			"lo0":   {net.ParseIP("127.0.0.1")},
+			"en0":   {net.ParseIP("10.0.1.5")},
+			"en1":   {net.ParseIP("192.168.7.20")},
+			"utun0": {net.ParseIP("10.8.0.2")},
+			"en2":   {net.ParseIP("10.0.2.5")},
+		}
+		addrsOf := func(iface net.Interface) []net.IP {
+			return addrs[iface.Name]
+		}
+
+		Convey("When nothing is included or excluded", func() {
+			ipAddrs := selectIpAddrs(ifaces, addrsOf, nil, nil)
+
+			Convey("Then I expect every up, multicast, non loopback interface with an address", func() {
+				So(ipAddrs, ShouldResemble, []string{"10.0.1.5", "192.168.7.20"})
+			})
+		})
+
+		Convey("When an interface is included by name", func() {
+			ipAddrs := selectIpAddrs(ifaces, addrsOf, []string{"en1"}, nil)
+
+			Convey("Then I expect only that interface", func() {
+				So(ipAddrs, ShouldResemble, []string{"192.168.7.20"})
+			})
+		})
+
+		Convey("When an interface has several addresses", func() {
+			ifaces = append(ifaces, net.Interface{Name: "en3", Flags: up})
+			addrs["en3"] = []net.IP{net.ParseIP("192.168.1.5"), net.ParseIP("10.0.1.5")}
+
+			Convey("Then I expect an included subnet to bind the address in it", func() {
+				ipAddrs := selectIpAddrs(ifaces, addrsOf, []string{"en3"}, []string{"192.168.1.0/24"})
+				So(ipAddrs, ShouldResemble, []string{"10.0.1.5"})
+
+				ipAddrs = selectIpAddrs(ifaces[len(ifaces)-1:], addrsOf, []string{"10.0.1.0/24"}, nil)
+				So(ipAddrs, ShouldResemble, []string{"10.0.1.5"})
+			})
+
+			Convey("Then I expect an excluded subnet to drop only the address in it", func() {
+				ipAddrs := selectIpAddrs(ifaces[len(ifaces)-1:], addrsOf, nil, []string{"10.0.1.0/24"})
+				So(ipAddrs, ShouldResemble, []string{"192.168.1.5"})
+			})
+		})
+
+		Convey("When a subnet is excluded", func() {
+			ipAddrs := selectIpAddrs(ifaces, addrsOf, nil, []string{"192.168.0.0/16"})
+
+			Convey("Then I expect interfaces in that subnet to be skipped", func() {
+				So(ipAddrs, ShouldResemble, []string{"10.0.1.5"})
+			})
+		})
+	})
+}
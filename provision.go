@@ -0,0 +1,214 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"bytes"
+	"code.google.com/p/go.net/context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSetupHost is where a factory reset device listens on its own access point
+const DefaultSetupHost = "10.22.22.1:49152"
+
+var errNetworkFailed = errors.New("device was unable to join the network; check the passphrase")
+
+type AccessPoint struct {
+	SSID    string
+	Channel int
+	Signal  int
+	Auth    string
+	Encrypt string
+}
+
+// Provisioner joins a factory reset device to a wifi network.  Connect to
+// the device's own access point (WeMo.Switch.XXX etc.) before using it
+type Provisioner struct {
+	Device *Device
+
+	// PollInterval between GetNetworkStatus calls; defaults to 2s
+	PollInterval time.Duration
+}
+
+func NewProvisioner() *Provisioner {
+	return &Provisioner{Device: &Device{Host: DefaultSetupHost}}
+}
+
+// parseApList parses GetApList e.g.
+// Page:1/1/2$
+// Home|6|100|WPA2PSK/AES,
+// Guest|11|42|OPEN/NONE,
+func parseApList(value string) []AccessPoint {
+	var accessPoints []AccessPoint
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if line == "" || strings.HasPrefix(line, "Page:") {
+			continue
+		}
+
+		fields := strings.Split(line, "|")
+		if len(fields) != 4 {
+			continue
+		}
+
+		channel, _ := strconv.Atoi(fields[1])
+		signal, _ := strconv.Atoi(fields[2])
+		security := strings.SplitN(fields[3], "/", 2)
+		ap := AccessPoint{
+			SSID:    fields[0],
+			Channel: channel,
+			Signal:  signal,
+			Auth:    security[0],
+		}
+		if len(security) == 2 {
+			ap.Encrypt = security[1]
+		}
+		accessPoints = append(accessPoints, ap)
+	}
+	return accessPoints
+}
+
+// AccessPoints lists the networks the device can see
+func (p *Provisioner) AccessPoints(ctx context.Context) ([]AccessPoint, error) {
+	response, err := p.Device.Call(ctx, ServiceWiFiSetup, "GetApList", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseApList(response.Args["ApList"]), nil
+}
+
+// encryptPassword encrypts the passphrase the way the WeMo app does.  The
+// key material is built from the device's mac address and serial number and
+// fed through OpenSSL's salted MD5 key derivation, i.e. the equivalent of
+// openssl enc -aes-128-cbc -md md5 -S <salt> -iv <iv> -pass pass:<keydata> -a.
+// The lengths of the result and of the passphrase are appended as two hex
+// digits each
+func encryptPassword(passphrase, macAddress, serialNumber string) (string, error) {
+	if len(macAddress) != 12 {
+		return "", fmt.Errorf("expected a 12 digit mac address => %s", macAddress)
+	}
+
+	keydata := []byte(macAddress[:6] + serialNumber + macAddress[6:])
+	if len(keydata) < 16 {
+		return "", fmt.Errorf("serial number too short => %s", serialNumber)
+	}
+	salt, iv := keydata[:8], keydata[:16]
+
+	// EVP_BytesToKey with a single round of md5 yields the 16 byte key
+	sum := md5.Sum(append(append([]byte{}, keydata...), salt...))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	// pkcs#7 padding
+	padding := aes.BlockSize - len(passphrase)%aes.BlockSize
+	plaintext := append([]byte(passphrase), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	encrypted := base64.StdEncoding.EncodeToString(ciphertext)
+	return fmt.Sprintf("%s%02x%02x", encrypted, len(encrypted), len(passphrase)), nil
+}
+
+// Connect joins the device to the named network, waits for it to report
+// success and then closes setup, at which point the device drops its own
+// access point and reboots onto the home network
+func (p *Provisioner) Connect(ctx context.Context, ssid, passphrase string) error {
+	accessPoints, err := p.AccessPoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ap *AccessPoint
+	for i := range accessPoints {
+		if accessPoints[i].SSID == ssid {
+			ap = &accessPoints[i]
+			break
+		}
+	}
+	if ap == nil {
+		return fmt.Errorf("device can't see network %s", ssid)
+	}
+
+	password := ""
+	if ap.Auth != "OPEN" {
+		deviceInfo, err := p.Device.FetchDeviceInfo(ctx)
+		if err != nil {
+			return err
+		}
+
+		password, err = encryptPassword(passphrase, deviceInfo.MacAddress, deviceInfo.SerialNumber)
+		if err != nil {
+			return err
+		}
+	}
+
+	args := struct {
+		SSID     string `xml:"ssid"`
+		Auth     string `xml:"auth"`
+		Password string `xml:"password"`
+		Encrypt  string `xml:"encrypt"`
+		Channel  int    `xml:"channel"`
+	}{ap.SSID, ap.Auth, password, ap.Encrypt, ap.Channel}
+	if _, err := p.Device.Call(ctx, ServiceWiFiSetup, "ConnectHomeNetwork", args); err != nil {
+		return err
+	}
+
+	if err := p.waitForNetwork(ctx); err != nil {
+		return err
+	}
+
+	_, err = p.Device.Call(ctx, ServiceWiFiSetup, "CloseSetup", nil)
+	return err
+}
+
+// waitForNetwork polls GetNetworkStatus: 1 means connected, 3 connected but
+// unable to reach Belkin's cloud, 2 failed and anything else still trying
+func (p *Provisioner) waitForNetwork(ctx context.Context) error {
+	interval := p.PollInterval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	for {
+		response, err := p.Device.Call(ctx, ServiceWiFiSetup, "GetNetworkStatus", nil)
+		if err != nil {
+			return err
+		}
+
+		switch strings.TrimSpace(response.Args["NetworkStatus"]) {
+		case "1", "3":
+			return nil
+		case "2":
+			return errNetworkFailed
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestEncryptPassword(t *testing.T) {
+	Convey("Given a passphrase, mac address and serial number", t, func() {
+		Convey("When I call #encryptPassword", func() {
+			encrypted, err := encryptPassword("hunter22", "EC1A5974B1EC", "221248K0102C92")
+
+			Convey("Then I expect the same result as openssl plus the lengths", func() {
+				So(err, ShouldBeNil)
+				So(encrypted, ShouldEqual, "9NTv/UMb7SvBBuAgDCZTIQ==1808")
+			})
+		})
+	})
+}
+
+func TestParseApList(t *testing.T) {
+	Convey("Given an ApList", t, func() {
+		value := "Page:1/1/2$\nHome|6|100|WPA2PSK/AES,\nGuest|11|42|OPEN/NONE,\n"
+
+		Convey("Then I expect each access point to be parsed", func() {
+			accessPoints := parseApList(value)
+			So(len(accessPoints), ShouldEqual, 2)
+			So(accessPoints[0], ShouldResemble, AccessPoint{SSID: "Home", Channel: 6, Signal: 100, Auth: "WPA2PSK", Encrypt: "AES"})
+			So(accessPoints[1].Auth, ShouldEqual, "OPEN")
+		})
+	})
+}
+
+func TestProvisioner(t *testing.T) {
+	Convey("Given a factory reset device", t, func() {
+		var connect map[string]string
+		var closed bool
+		statuses := []string{"0", "1"}
+		fake := &fakeDevice{
+			DeviceType:   "urn:Belkin:device:controllee:1",
+			MacAddress:   "EC1A5974B1EC",
+			SerialNumber: "221248K0102C92",
+			Services:     []string{ServiceWiFiSetup},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				switch action {
+				case "GetApList":
+					return map[string]string{"ApList": "Page:1/1/1$\nHome|6|100|WPA2PSK/AES,\n"}, nil
+				case "ConnectHomeNetwork":
+					connect = args
+				case "GetNetworkStatus":
+					status := statuses[0]
+					statuses = statuses[1:]
+					return map[string]string{"NetworkStatus": status}, nil
+				case "CloseSetup":
+					closed = true
+				}
+				return nil, nil
+			},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		provisioner := &Provisioner{Device: device, PollInterval: time.Millisecond}
+
+		Convey("When I connect to a network the device can see", func() {
+			err := provisioner.Connect(context.Background(), "Home", "hunter22")
+
+			Convey("Then I expect the encrypted network details to be sent and setup closed", func() {
+				So(err, ShouldBeNil)
+				So(connect["ssid"], ShouldEqual, "Home")
+				So(connect["auth"], ShouldEqual, "WPA2PSK")
+				So(connect["password"], ShouldEqual, "9NTv/UMb7SvBBuAgDCZTIQ==1808")
+				So(connect["encrypt"], ShouldEqual, "AES")
+				So(connect["channel"], ShouldEqual, "6")
+				So(closed, ShouldBeTrue)
+			})
+		})
+
+		Convey("When I connect to a network the device can't see", func() {
+			err := provisioner.Connect(context.Background(), "Neighbor", "secret")
+
+			Convey("Then I expect an error", func() {
+				So(err, ShouldNotBeNil)
+				So(closed, ShouldBeFalse)
+			})
+		})
+	})
+}
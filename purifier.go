@@ -0,0 +1,101 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"strconv"
+)
+
+const AirPurifierURN = "urn:Belkin:device:AirPurifier:1"
+
+type PurifierMode int
+
+const (
+	PurifierOff    PurifierMode = 0
+	PurifierLow    PurifierMode = 1
+	PurifierMedium PurifierMode = 2
+	PurifierHigh   PurifierMode = 3
+	PurifierAuto   PurifierMode = 4
+)
+
+var purifierModes = map[PurifierMode]string{
+	PurifierOff:    "off",
+	PurifierLow:    "low",
+	PurifierMedium: "medium",
+	PurifierHigh:   "high",
+	PurifierAuto:   "auto",
+}
+
+func (m PurifierMode) String() string {
+	if name, ok := purifierModes[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("PurifierMode(%d)", int(m))
+}
+
+type AirQuality int
+
+const (
+	AirQualityPoor     AirQuality = 0
+	AirQualityModerate AirQuality = 1
+	AirQualityGood     AirQuality = 2
+)
+
+type AirPurifierState struct {
+	Mode          PurifierMode `json:"mode"`
+	Ionizer       bool         `json:"ionizer"`
+	AirQuality    AirQuality   `json:"air-quality"`
+	FilterLife    float64      `json:"filter-life"` // percent remaining
+	FilterExpired bool         `json:"filter-expired"`
+}
+
+// AirPurifier is the WeMo enabled Holmes air purifier
+type AirPurifier struct {
+	*Device
+}
+
+func (a *AirPurifier) State(ctx context.Context) (*AirPurifierState, error) {
+	attributes, err := a.GetAttributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AirPurifierState{
+		Mode:          PurifierMode(attributeInt(attributes, "Mode")),
+		Ionizer:       attributes["Ionizer"] == "1",
+		AirQuality:    AirQuality(attributeInt(attributes, "AirQuality")),
+		FilterLife:    attributeFloat(attributes, "FilterLife") * 100 / filterLifeMinutes,
+		FilterExpired: attributes["ExpiredFilterTime"] == "1",
+	}, nil
+}
+
+func (a *AirPurifier) SetMode(ctx context.Context, mode PurifierMode) error {
+	if _, ok := purifierModes[mode]; !ok {
+		return fmt.Errorf("unknown purifier mode => %d", int(mode))
+	}
+
+	return a.SetAttributes(ctx, map[string]string{
+		"Mode": strconv.Itoa(int(mode)),
+	})
+}
+
+func (a *AirPurifier) SetIonizer(ctx context.Context, on bool) error {
+	value := "0"
+	if on {
+		value = "1"
+	}
+	return a.SetAttributes(ctx, map[string]string{"Ionizer": value})
+}
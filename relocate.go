@@ -0,0 +1,121 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// devicePorts lists the ports a device may listen on; it picks one at boot
+var devicePorts = []int{49152, 49153, 49154, 49155}
+
+// relocateTimeout bounds each probe of a sibling port
+var relocateTimeout = 2 * time.Second
+
+func isConnectionRefused(err error) bool {
+	return err != nil && errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// CurrentHost returns the host the device was last found on.  It differs from
+// Host once the device has moved to another port
+func (d *Device) CurrentHost() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.current != "" {
+		return d.current
+	}
+	return d.Host
+}
+
+// identity returns the UDN used to recognize the device on another port
+func (d *Device) identity() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.udn != "" {
+		return d.udn
+	}
+	return d.UDN
+}
+
+// relocate probes the other device ports on the same ip after a request to
+// oldHost was refused.  A device is only accepted if its UDN matches
+// Device.UDN or the one previously seen in setup.xml, so nothing is probed
+// until the device's identity is known.  cause is returned if the device
+// can't be found
+func (d *Device) relocate(ctx context.Context, oldHost string, cause error) (*DeviceInfo, error) {
+	d.relocating.Lock()
+	defer d.relocating.Unlock()
+
+	// another call already found the device
+	if current := d.CurrentHost(); current != oldHost {
+		deviceInfo, err := fetchDeviceInfo(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		deviceInfo.Device = d
+		return deviceInfo, nil
+	}
+
+	udn := d.identity()
+	if udn == "" {
+		d.printf("unable to look for %s on other ports; its UDN is unknown\n", oldHost)
+		return nil, cause
+	}
+
+	ip, port, err := net.SplitHostPort(oldHost)
+	if err != nil {
+		return nil, cause
+	}
+
+	for _, candidate := range devicePorts {
+		if strconv.Itoa(candidate) == port {
+			continue
+		}
+
+		newHost := net.JoinHostPort(ip, strconv.Itoa(candidate))
+		probe, cancel := context.WithTimeout(ctx, relocateTimeout)
+		deviceInfo, err := fetchDeviceInfo(probe, newHost)
+		cancel()
+		if err != nil {
+			continue
+		}
+		if deviceInfo.UDN != udn {
+			d.printf("%s is a different device, %s\n", newHost, deviceInfo.UDN)
+			continue
+		}
+
+		d.mutex.Lock()
+		d.current = newHost
+		d.services = deviceInfo.Services
+		d.udn = deviceInfo.UDN
+		d.mutex.Unlock()
+
+		d.printf("device moved from %s to %s\n", oldHost, newHost)
+		if d.OnHostChange != nil {
+			d.OnHostChange(oldHost, newHost)
+		}
+
+		deviceInfo.Device = d
+		return deviceInfo, nil
+	}
+
+	return nil, cause
+}
@@ -0,0 +1,136 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// closedPort returns a local port with nothing listening on it
+func closedPort() int {
+	listener, _ := net.Listen("tcp4", "127.0.0.1:0")
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func portOf(host string) int {
+	_, port, _ := net.SplitHostPort(host)
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+func TestRelocate(t *testing.T) {
+	Convey("Given a device that moved to another port", t, func() {
+		fake := &fakeDevice{
+			DeviceType: "urn:Belkin:device:controllee:1",
+			UDN:        "uuid:Socket-1_0-221326K0101234",
+			Services:   []string{ServiceBasicEvent},
+			Handler: func(serviceType, action string, args map[string]string) (map[string]string, error) {
+				return map[string]string{"BinaryState": "1"}, nil
+			},
+		}
+		moved, stop := fake.start()
+		defer stop()
+
+		other := &fakeDevice{
+			DeviceType: "urn:Belkin:device:controllee:1",
+			UDN:        "uuid:Socket-1_0-221326K0109999",
+			Services:   []string{ServiceBasicEvent},
+		}
+		imposter, stopImposter := other.start()
+		defer stopImposter()
+
+		oldPort := closedPort()
+		saved := devicePorts
+		devicePorts = []int{oldPort, portOf(imposter.Host), portOf(moved.Host)}
+		defer func() { devicePorts = saved }()
+
+		oldHost := net.JoinHostPort("127.0.0.1", strconv.Itoa(oldPort))
+		device := &Device{
+			Host: oldHost,
+			UDN:  fake.UDN,
+		}
+
+		var mutex sync.Mutex
+		var changes []string
+		device.OnHostChange = func(from, to string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			changes = append(changes, from, to)
+		}
+
+		Convey("When I call an action", func() {
+			response, err := device.Call(context.Background(), ServiceBasicEvent, "GetBinaryState", nil)
+
+			Convey("Then I expect the call to be retried on the device's new port", func() {
+				So(err, ShouldBeNil)
+				So(response.Args["BinaryState"], ShouldEqual, "1")
+				So(device.CurrentHost(), ShouldEqual, moved.Host)
+				So(device.Host, ShouldEqual, oldHost)
+			})
+
+			Convey("Then I expect the change to be reported", func() {
+				So(changes, ShouldResemble, []string{oldHost, moved.Host})
+			})
+		})
+
+		Convey("When several calls are refused at once", func() {
+			var wg sync.WaitGroup
+			errs := make(chan error, 4)
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := device.Call(context.Background(), ServiceBasicEvent, "GetBinaryState", nil)
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			Convey("Then I expect every call to succeed and one change to be reported", func() {
+				for err := range errs {
+					So(err, ShouldBeNil)
+				}
+				So(changes, ShouldResemble, []string{oldHost, moved.Host})
+			})
+		})
+
+		Convey("When no sibling port has the same device", func() {
+			devicePorts = []int{oldPort, portOf(imposter.Host)}
+			_, err := device.FetchDeviceInfo(context.Background())
+
+			Convey("Then I expect the original error and no change", func() {
+				So(isConnectionRefused(err), ShouldBeTrue)
+				So(device.CurrentHost(), ShouldEqual, oldHost)
+				So(changes, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the device's identity is unknown", func() {
+			stranger := &Device{Host: oldHost}
+			_, err := stranger.Call(context.Background(), ServiceBasicEvent, "GetBinaryState", nil)
+
+			Convey("Then I expect it not to adopt whatever answers on another port", func() {
+				So(isConnectionRefused(err), ShouldBeTrue)
+				So(stranger.CurrentHost(), ShouldEqual, oldHost)
+			})
+		})
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rules
+
+import (
+	"archive/zip"
+	"bytes"
+	"code.google.com/p/go.net/context"
+	"encoding/base64"
+	"fmt"
+	"github.com/savaki/go.wemo"
+	"github.com/savaki/httpctx"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// the name the firmware gives the database inside the zip
+const dbName = "temppluginRules.db"
+
+// unzip returns the first sqlite database found in the archive
+func unzip(data []byte) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range reader.File {
+		if path.Ext(file.Name) != ".db" {
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return ioutil.ReadAll(r)
+	}
+
+	return nil, fmt.Errorf("no rules database found in zip")
+}
+
+func zipDB(data []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	writer := zip.NewWriter(buf)
+
+	w, err := writer.Create(dbName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Fetch downloads the device's rules database.  Devices that have never had
+// rules return an empty database.  Close the DB when done with it
+func Fetch(ctx context.Context, device *wemo.Device) (*DB, error) {
+	response, err := device.Call(ctx, wemo.ServiceRules, "FetchRules", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	version, _ := strconv.Atoi(strings.TrimSpace(response.Args["ruleDbVersion"]))
+	uri := strings.TrimSpace(response.Args["ruleDbPath"])
+	if uri == "" {
+		return openBytes(nil, version)
+	}
+
+	var data []byte
+	err = httpctx.NewClient().Get(ctx, uri, nil, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := unzip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return openBytes(db, version)
+}
+
+// Store uploads the database to the device as version Version+1 and, on
+// success, updates Version
+func (d *DB) Store(ctx context.Context, device *wemo.Device) error {
+	data, err := d.bytes()
+	if err != nil {
+		return err
+	}
+
+	zipped, err := zipDB(data)
+	if err != nil {
+		return err
+	}
+
+	args := struct {
+		RuleDbVersion int    `xml:"ruleDbVersion"`
+		ProcessDb     int    `xml:"processDb"`
+		RuleDbBody    string `xml:"ruleDbBody"`
+	}{
+		RuleDbVersion: d.Version + 1,
+		ProcessDb:     1,
+		RuleDbBody:    "<![CDATA[" + base64.StdEncoding.EncodeToString(zipped) + "]]>",
+	}
+
+	_, err = device.Call(ctx, wemo.ServiceRules, "StoreRules", args)
+	if err != nil {
+		return err
+	}
+
+	d.Version = args.RuleDbVersion
+	return nil
+}
@@ -0,0 +1,305 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rules reads and edits the rules database WeMo devices use to run
+// schedules locally.  The database is a zipped SQLite file exchanged through
+// the FetchRules and StoreRules actions of the rules service
+package rules
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// rule types understood by the firmware
+const (
+	TypeTimeInterval = "Time Interval"
+	TypeSimpleSwitch = "Simple Switch"
+	TypeCountdown    = "Countdown Rule"
+	TypeAwayMode     = "Away Mode"
+)
+
+type Rule struct {
+	RuleID    int
+	Name      string
+	Type      string
+	RuleOrder int
+	StartDate string
+	EndDate   string
+	State     string
+	Sync      string
+}
+
+// RuleDevice describes when and how a rule fires; there's one row per day
+type RuleDevice struct {
+	RuleDevicePK      int
+	RuleID            int
+	DeviceID          string
+	GroupID           int
+	DayID             int
+	StartTime         int
+	RuleDuration      int
+	StartAction       float64
+	EndAction         float64
+	SensorDuration    int
+	Type              int
+	Value             int
+	Level             int
+	ZBCapabilityStart string
+	ZBCapabilityEnd   string
+	OnModeOffset      int
+	OffModeOffset     int
+	CountdownTime     int
+	EndTime           int
+}
+
+// TargetDevice is a device, other than the one holding the rule, that the rule controls
+type TargetDevice struct {
+	TargetDevicesPK int
+	RuleID          int
+	DeviceID        string
+	DeviceIndex     int
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS RULES(RuleID PRIMARY KEY, Name TEXT NOT NULL, Type TEXT NOT NULL, RuleOrder INTEGER, StartDate TEXT, EndDate TEXT, State TEXT, Sync INTEGER);
+CREATE TABLE IF NOT EXISTS RULEDEVICES(RuleDevicePK INTEGER PRIMARY KEY AUTOINCREMENT, RuleID INTEGER, DeviceID TEXT, GroupID INTEGER, DayID INTEGER, StartTime INTEGER, RuleDuration INTEGER, StartAction REAL, EndAction REAL, SensorDuration INTEGER, Type INTEGER, Value INTEGER, Level INTEGER, ZBCapabilityStart TEXT, ZBCapabilityEnd TEXT, OnModeOffset INTEGER, OffModeOffset INTEGER, CountdownTime INTEGER, EndTime INTEGER);
+CREATE TABLE IF NOT EXISTS TARGETDEVICES(TargetDevicesPK INTEGER PRIMARY KEY AUTOINCREMENT, RuleID INTEGER, DeviceID TEXT, DeviceIndex INTEGER);
+CREATE TABLE IF NOT EXISTS LOCATIONINFO(LocationPk INTEGER PRIMARY KEY AUTOINCREMENT, cityName TEXT, countryName TEXT, latitude TEXT, longitude TEXT, countryCode TEXT, region TEXT);
+`
+
+// DB is a local copy of a device's rules database
+type DB struct {
+	// Version is the rules version the device reported; Store uploads Version+1
+	Version int
+
+	path string
+	db   *sql.DB
+}
+
+// open opens the sqlite file at path, creating the tables the firmware
+// expects if the device didn't have a database yet
+func open(path string, version int) (*DB, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &DB{Version: version, path: path, db: db}, nil
+}
+
+// openBytes writes data to a temp file and opens it
+func openBytes(data []byte, version int) (*DB, error) {
+	file, err := ioutil.TempFile("", "wemo-rules-")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	db, err := open(file.Name(), version)
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return db, nil
+}
+
+// Close releases the database and removes the local copy
+func (d *DB) Close() error {
+	err := d.db.Close()
+	os.Remove(d.path)
+	return err
+}
+
+// bytes returns the contents of the sqlite file
+func (d *DB) bytes() ([]byte, error) {
+	return ioutil.ReadFile(d.path)
+}
+
+func (d *DB) Rules() ([]Rule, error) {
+	rows, err := d.db.Query(`SELECT RuleID, Name, Type, COALESCE(RuleOrder, 0), COALESCE(StartDate, ''), COALESCE(EndDate, ''), COALESCE(State, ''), COALESCE(Sync, '') FROM RULES ORDER BY RuleOrder, RuleID`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		rule := Rule{}
+		err := rows.Scan(&rule.RuleID, &rule.Name, &rule.Type, &rule.RuleOrder, &rule.StartDate, &rule.EndDate, &rule.State, &rule.Sync)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (d *DB) RuleDevices(ruleID int) ([]RuleDevice, error) {
+	rows, err := d.db.Query(`SELECT RuleDevicePK, RuleID, COALESCE(DeviceID, ''), COALESCE(GroupID, 0), COALESCE(DayID, 0), COALESCE(StartTime, 0), COALESCE(RuleDuration, 0), COALESCE(StartAction, 0), COALESCE(EndAction, 0), COALESCE(SensorDuration, 0), COALESCE(Type, 0), COALESCE(Value, 0), COALESCE(Level, 0), COALESCE(ZBCapabilityStart, ''), COALESCE(ZBCapabilityEnd, ''), COALESCE(OnModeOffset, 0), COALESCE(OffModeOffset, 0), COALESCE(CountdownTime, 0), COALESCE(EndTime, 0) FROM RULEDEVICES WHERE RuleID = ? ORDER BY DayID, StartTime`, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []RuleDevice
+	for rows.Next() {
+		r := RuleDevice{}
+		err := rows.Scan(&r.RuleDevicePK, &r.RuleID, &r.DeviceID, &r.GroupID, &r.DayID, &r.StartTime, &r.RuleDuration, &r.StartAction, &r.EndAction, &r.SensorDuration, &r.Type, &r.Value, &r.Level, &r.ZBCapabilityStart, &r.ZBCapabilityEnd, &r.OnModeOffset, &r.OffModeOffset, &r.CountdownTime, &r.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, r)
+	}
+
+	return devices, rows.Err()
+}
+
+func (d *DB) TargetDevices(ruleID int) ([]TargetDevice, error) {
+	rows, err := d.db.Query(`SELECT TargetDevicesPK, RuleID, COALESCE(DeviceID, ''), COALESCE(DeviceIndex, 0) FROM TARGETDEVICES WHERE RuleID = ? ORDER BY DeviceIndex`, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []TargetDevice
+	for rows.Next() {
+		t := TargetDevice{}
+		if err := rows.Scan(&t.TargetDevicesPK, &t.RuleID, &t.DeviceID, &t.DeviceIndex); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, rows.Err()
+}
+
+// AddRule inserts the rule along with its devices and targets, assigning the
+// next free RuleID, which is returned
+func (d *DB) AddRule(rule Rule, devices []RuleDevice, targets []TargetDevice) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	var ruleID, ruleOrder int
+	err = tx.QueryRow(`SELECT COALESCE(MAX(CAST(RuleID AS INTEGER)), 0) + 1, COALESCE(MAX(RuleOrder), -1) + 1 FROM RULES`).Scan(&ruleID, &ruleOrder)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if rule.RuleOrder == 0 {
+		rule.RuleOrder = ruleOrder
+	}
+
+	_, err = tx.Exec(`INSERT INTO RULES(RuleID, Name, Type, RuleOrder, StartDate, EndDate, State, Sync) VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		ruleID, rule.Name, rule.Type, rule.RuleOrder, rule.StartDate, rule.EndDate, rule.State, rule.Sync)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for _, r := range devices {
+		_, err = tx.Exec(`INSERT INTO RULEDEVICES(RuleID, DeviceID, GroupID, DayID, StartTime, RuleDuration, StartAction, EndAction, SensorDuration, Type, Value, Level, ZBCapabilityStart, ZBCapabilityEnd, OnModeOffset, OffModeOffset, CountdownTime, EndTime) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			ruleID, r.DeviceID, r.GroupID, r.DayID, r.StartTime, r.RuleDuration, r.StartAction, r.EndAction, r.SensorDuration, r.Type, r.Value, r.Level, r.ZBCapabilityStart, r.ZBCapabilityEnd, r.OnModeOffset, r.OffModeOffset, r.CountdownTime, r.EndTime)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	for _, t := range targets {
+		_, err = tx.Exec(`INSERT INTO TARGETDEVICES(RuleID, DeviceID, DeviceIndex) VALUES(?, ?, ?)`, ruleID, t.DeviceID, t.DeviceIndex)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return ruleID, tx.Commit()
+}
+
+// RemoveRule deletes the rule along with its devices and targets; it is an
+// error for the rule not to exist
+func (d *DB) RemoveRule(ruleID int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range []string{
+		`DELETE FROM TARGETDEVICES WHERE RuleID = ?`,
+		`DELETE FROM RULEDEVICES WHERE RuleID = ?`,
+	} {
+		if _, err := tx.Exec(statement, ruleID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM RULES WHERE RuleID = ?`, ruleID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n == 0 {
+		tx.Rollback()
+		return fmt.Errorf("no schedule with id %d", ruleID)
+	}
+
+	return tx.Commit()
+}
+
+// SetLocation records where the device is so the firmware can work out
+// sunrise and sunset for itself
+func (d *DB) SetLocation(latitude, longitude float64) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM LOCATIONINFO`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO LOCATIONINFO(cityName, countryName, latitude, longitude, countryCode, region) VALUES('', '', ?, ?, '', '')`,
+		strconv.FormatFloat(latitude, 'f', 6, 64), strconv.FormatFloat(longitude, 'f', 6, 64))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
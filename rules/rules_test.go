@@ -0,0 +1,148 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rules
+
+import (
+	"code.google.com/p/go.net/context"
+	"encoding/base64"
+	"fmt"
+	"github.com/savaki/go.wemo"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const setupXML = `<?xml version="1.0"?>
+<root xmlns="urn:Belkin:device-1-0">
+  <device>
+    <deviceType>urn:Belkin:device:controllee:1</deviceType>
+    <serviceList>
+      <service>
+        <serviceType>urn:Belkin:service:rules:1</serviceType>
+        <controlURL>/upnp/control/rules1</controlURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`
+
+var ruleDbBodyRE = regexp.MustCompile(`<ruleDbBody>&lt;!\[CDATA\[([^&]*)\]\]&gt;</ruleDbBody>`)
+var ruleDbVersionRE = regexp.MustCompile(`<ruleDbVersion>(\d+)</ruleDbVersion>`)
+
+// rulesDevice stores whatever database it's given and hands it back on FetchRules
+type rulesDevice struct {
+	version string
+	zipped  []byte
+}
+
+func (r *rulesDevice) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case "/setup.xml":
+		w.Write([]byte(setupXML))
+
+	case "/rules.db":
+		w.Write(r.zipped)
+
+	case "/upnp/control/rules1":
+		data, _ := ioutil.ReadAll(req.Body)
+		body := ""
+		if strings.Contains(req.Header.Get("SOAPACTION"), "#FetchRules") {
+			path := ""
+			if r.zipped != nil {
+				path = "http://" + req.Host + "/rules.db"
+			}
+			body = fmt.Sprintf(`<u:FetchRulesResponse xmlns:u="urn:Belkin:service:rules:1"><ruleDbVersion>%s</ruleDbVersion><ruleDbPath>%s</ruleDbPath></u:FetchRulesResponse>`, r.version, path)
+		} else {
+			r.version = ruleDbVersionRE.FindStringSubmatch(string(data))[1]
+			r.zipped, _ = base64.StdEncoding.DecodeString(ruleDbBodyRE.FindStringSubmatch(string(data))[1])
+			body = `<u:StoreRulesResponse xmlns:u="urn:Belkin:service:rules:1"><errorInfo>Storing of rules DB Successful</errorInfo></u:StoreRulesResponse>`
+		}
+		fmt.Fprintf(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>%s</s:Body></s:Envelope>`, body)
+
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func TestRules(t *testing.T) {
+	Convey("Given a device without any rules", t, func() {
+		server := httptest.NewServer(&rulesDevice{version: "0"})
+		defer server.Close()
+
+		device := &wemo.Device{Host: strings.TrimPrefix(server.URL, "http://")}
+		ctx := context.Background()
+
+		Convey("When I fetch the rules", func() {
+			db, err := Fetch(ctx, device)
+			So(err, ShouldBeNil)
+			defer db.Close()
+
+			Convey("Then I expect an empty database", func() {
+				rules, err := db.Rules()
+				So(err, ShouldBeNil)
+				So(len(rules), ShouldEqual, 0)
+			})
+
+			Convey("And I add a rule and store it", func() {
+				ruleID, err := db.AddRule(
+					Rule{Name: "Porch", Type: TypeTimeInterval, State: "1"},
+					[]RuleDevice{{DeviceID: "uuid:Socket-1_0-221248K0102C92", DayID: 2, StartTime: 3600, StartAction: 1, EndAction: -1}},
+					[]TargetDevice{{DeviceID: "uuid:Socket-1_0-221248K0102C93"}},
+				)
+				So(err, ShouldBeNil)
+				So(ruleID, ShouldEqual, 1)
+				So(db.Store(ctx, device), ShouldBeNil)
+
+				Convey("Then I expect the version to be bumped", func() {
+					So(db.Version, ShouldEqual, 1)
+				})
+
+				Convey("Then I expect to fetch it back", func() {
+					stored, err := Fetch(ctx, device)
+					So(err, ShouldBeNil)
+					defer stored.Close()
+
+					So(stored.Version, ShouldEqual, 1)
+
+					rules, _ := stored.Rules()
+					So(len(rules), ShouldEqual, 1)
+					So(rules[0].Name, ShouldEqual, "Porch")
+
+					devices, _ := stored.RuleDevices(ruleID)
+					So(len(devices), ShouldEqual, 1)
+					So(devices[0].StartTime, ShouldEqual, 3600)
+					So(devices[0].EndAction, ShouldEqual, -1)
+
+					targets, _ := stored.TargetDevices(ruleID)
+					So(len(targets), ShouldEqual, 1)
+				})
+
+				Convey("And I remove it", func() {
+					So(db.RemoveRule(ruleID), ShouldBeNil)
+
+					Convey("Then I expect no rules", func() {
+						rules, _ := db.Rules()
+						So(len(rules), ShouldEqual, 0)
+
+						devices, _ := db.RuleDevices(ruleID)
+						So(len(devices), ShouldEqual, 0)
+					})
+				})
+			})
+		})
+	})
+}
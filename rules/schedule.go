@@ -0,0 +1,477 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rules
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/savaki/go.wemo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Days is a set of weekdays
+type Days uint8
+
+const (
+	Sunday Days = 1 << iota
+	Monday
+	Tuesday
+	Wednesday
+	Thursday
+	Friday
+	Saturday
+
+	Weekdays = Monday | Tuesday | Wednesday | Thursday | Friday
+	Weekends = Saturday | Sunday
+	Daily    = Weekdays | Weekends
+)
+
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func (d Days) Has(day time.Weekday) bool {
+	return d&(1<<uint(day)) != 0
+}
+
+func (d Days) String() string {
+	switch d {
+	case Daily:
+		return "daily"
+	case Weekdays:
+		return "weekdays"
+	case Weekends:
+		return "weekends"
+	}
+
+	var names []string
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if d.Has(day) {
+			names = append(names, dayNames[day])
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseDays accepts daily, weekdays, weekends or a comma separated list of
+// days e.g. mon,wed,fri
+func ParseDays(value string) (Days, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "daily", "":
+		return Daily, nil
+	case "weekdays":
+		return Weekdays, nil
+	case "weekends":
+		return Weekends, nil
+	}
+
+	var days Days
+	for _, name := range strings.Split(strings.ToLower(value), ",") {
+		found := false
+		for i, dayName := range dayNames {
+			if strings.HasPrefix(strings.TrimSpace(name), dayName) {
+				days |= 1 << uint(i)
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown day => %s", name)
+		}
+	}
+	return days, nil
+}
+
+type Action int
+
+const (
+	Off    Action = 0
+	On     Action = 1
+	Toggle Action = 2
+)
+
+func (a Action) String() string {
+	switch a {
+	case Off:
+		return "off"
+	case On:
+		return "on"
+	case Toggle:
+		return "toggle"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+func ParseAction(value string) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on":
+		return On, nil
+	case "off":
+		return Off, nil
+	case "toggle":
+		return Toggle, nil
+	default:
+		return Off, fmt.Errorf("unknown action => %s", value)
+	}
+}
+
+type anchor int
+
+const (
+	anchorClock anchor = iota
+	anchorSunrise
+	anchorSunset
+)
+
+// At is when a schedule fires: a time of day, or sunrise or sunset plus an
+// offset e.g. Sunset.Offset(-15*time.Minute)
+type At struct {
+	anchor anchor
+	offset time.Duration
+}
+
+var (
+	Sunrise = At{anchor: anchorSunrise}
+	Sunset  = At{anchor: anchorSunset}
+)
+
+// Clock returns a fixed time of day
+func Clock(hour, minute int) At {
+	return At{offset: time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute}
+}
+
+func (a At) Offset(d time.Duration) At {
+	a.offset += d
+	return a
+}
+
+func (a At) IsSun() bool {
+	return a.anchor != anchorClock
+}
+
+func (a At) String() string {
+	switch a.anchor {
+	case anchorSunrise, anchorSunset:
+		name := "sunrise"
+		if a.anchor == anchorSunset {
+			name = "sunset"
+		}
+		if a.offset == 0 {
+			return name
+		}
+		if a.offset > 0 {
+			return name + "+" + a.offset.String()
+		}
+		return name + a.offset.String()
+	default:
+		return fmt.Sprintf("%02d:%02d", int(a.offset/time.Hour), int(a.offset%time.Hour/time.Minute))
+	}
+}
+
+// ParseAt accepts a 24 hour time e.g. 07:30, or sunrise or sunset with an
+// optional offset e.g. sunset-15m
+func ParseAt(value string) (At, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	for _, sun := range []At{Sunrise, Sunset} {
+		name := sun.String()
+		if !strings.HasPrefix(value, name) {
+			continue
+		}
+		if rest := value[len(name):]; rest != "" {
+			offset, err := time.ParseDuration(rest)
+			if err != nil {
+				return At{}, fmt.Errorf("invalid offset => %s", value)
+			}
+			return sun.Offset(offset), nil
+		}
+		return sun, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return At{}, fmt.Errorf("expected HH:MM, sunrise or sunset => %s", value)
+	}
+	hour, err1 := strconv.Atoi(parts[0])
+	minute, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return At{}, fmt.Errorf("invalid time of day => %s", value)
+	}
+	return Clock(hour, minute), nil
+}
+
+// seconds returns the time of day, in seconds after midnight, on the given date
+func (a At) seconds(date time.Time, latitude, longitude float64) (int, error) {
+	if !a.IsSun() {
+		return int(a.offset / time.Second), nil
+	}
+
+	sunrise, sunset, err := sunriseSunset(date, latitude, longitude)
+	if err != nil {
+		return 0, err
+	}
+
+	t := sunrise
+	if a.anchor == anchorSunset {
+		t = sunset
+	}
+	t = t.Add(a.offset)
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return int(t.Sub(midnight) / time.Second), nil
+}
+
+type ScheduleType int
+
+const (
+	// Timer switches the device at a time of day
+	Timer ScheduleType = iota
+	// Countdown performs Action a fixed time after the device is turned on
+	Countdown
+	// Away randomly switches the device between At and Until to make the
+	// house look occupied
+	Away
+)
+
+type Schedule struct {
+	Name   string
+	Type   ScheduleType
+	Days   Days
+	At     At
+	Action Action
+
+	// Until ends an Away schedule
+	Until At
+
+	// Delay is how long a Countdown waits e.g. turn off 30 minutes after turned on
+	Delay time.Duration
+}
+
+// values the firmware uses in RULEDEVICES
+const (
+	noAction       = -1
+	dayDaily       = -1
+	sunriseType    = 1
+	sunsetType     = 2
+	secondsPerDay  = 24 * 60 * 60
+	neverStartDate = "12201982"
+	neverEndDate   = "07301982"
+)
+
+// dayID maps a weekday to the DayID the firmware uses; 1 is Sunday
+func dayID(day time.Weekday) int {
+	return int(day) + 1
+}
+
+// Scheduler manages the schedules stored on a device.  Each change downloads
+// the rules database, edits it and stores it back, so schedules keep running
+// on the device with nothing else on the network
+type Scheduler struct {
+	Device *wemo.Device
+
+	// Latitude and Longitude of the device are required for sunrise and sunset schedules
+	Latitude  float64
+	Longitude float64
+
+	// Location is the device's time zone; defaults to time.Local
+	Location *time.Location
+}
+
+func Schedules(device *wemo.Device) *Scheduler {
+	return &Scheduler{Device: device}
+}
+
+// ScheduledRule is a rule as stored on the device
+type ScheduledRule struct {
+	Rule
+	Devices []RuleDevice
+}
+
+func (s *Scheduler) List(ctx context.Context) ([]ScheduledRule, error) {
+	db, err := Fetch(ctx, s.Device)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rules, err := db.Rules()
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []ScheduledRule
+	for _, rule := range rules {
+		devices, err := db.RuleDevices(rule.RuleID)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, ScheduledRule{Rule: rule, Devices: devices})
+	}
+	return scheduled, nil
+}
+
+// rows translates a schedule into the rule and RULEDEVICES rows the firmware expects
+func (s *Scheduler) rows(schedule Schedule, deviceID string, now time.Time) (Rule, []RuleDevice, error) {
+	rule := Rule{
+		Name:      schedule.Name,
+		StartDate: neverStartDate,
+		EndDate:   neverEndDate,
+		State:     "1",
+		Sync:      "NOSYNC",
+	}
+
+	if schedule.Type == Countdown {
+		if schedule.Delay <= 0 {
+			return Rule{}, nil, fmt.Errorf("countdown requires a positive delay")
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("%s after %s", schedule.Action, schedule.Delay)
+		}
+		rule.Type = TypeCountdown
+		return rule, []RuleDevice{{
+			DeviceID:      deviceID,
+			DayID:         dayDaily,
+			StartTime:     0,
+			RuleDuration:  secondsPerDay,
+			StartAction:   float64(On),
+			EndAction:     float64(schedule.Action),
+			CountdownTime: int(schedule.Delay / time.Second),
+			EndTime:       secondsPerDay,
+		}}, nil
+	}
+
+	if schedule.Days == 0 {
+		return Rule{}, nil, fmt.Errorf("schedule requires at least one day")
+	}
+
+	start, err := schedule.At.seconds(now, s.Latitude, s.Longitude)
+	if err != nil {
+		return Rule{}, nil, err
+	}
+
+	template := RuleDevice{
+		DeviceID:    deviceID,
+		StartTime:   start,
+		StartAction: float64(schedule.Action),
+		EndAction:   noAction,
+	}
+	if schedule.At.IsSun() {
+		template.Type = sunriseType
+		if schedule.At.anchor == anchorSunset {
+			template.Type = sunsetType
+		}
+		template.OnModeOffset = int(schedule.At.offset / time.Second)
+	}
+
+	switch schedule.Type {
+	case Timer:
+		rule.Type = TypeTimeInterval
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("%s at %s", schedule.Action, schedule.At)
+		}
+
+	case Away:
+		end, err := schedule.Until.seconds(now, s.Latitude, s.Longitude)
+		if err != nil {
+			return Rule{}, nil, err
+		}
+		if end <= start {
+			end += secondsPerDay
+		}
+
+		rule.Type = TypeAwayMode
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("away %s to %s", schedule.At, schedule.Until)
+		}
+		template.StartAction = float64(On)
+		template.EndAction = float64(Off)
+		template.RuleDuration = end - start
+		template.EndTime = end
+		if schedule.Until.IsSun() {
+			template.OffModeOffset = int(schedule.Until.offset / time.Second)
+		}
+
+	default:
+		return Rule{}, nil, fmt.Errorf("unknown schedule type => %d", int(schedule.Type))
+	}
+
+	var devices []RuleDevice
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if schedule.Days.Has(day) {
+			row := template
+			row.DayID = dayID(day)
+			devices = append(devices, row)
+		}
+	}
+
+	return rule, devices, nil
+}
+
+func (s *Scheduler) location() *time.Location {
+	if s.Location != nil {
+		return s.Location
+	}
+	return time.Local
+}
+
+// Add stores the schedule on the device and returns its rule id
+func (s *Scheduler) Add(ctx context.Context, schedule Schedule) (int, error) {
+	if (schedule.At.IsSun() || schedule.Until.IsSun()) && s.Latitude == 0 && s.Longitude == 0 {
+		return 0, fmt.Errorf("sunrise and sunset schedules require the device's latitude and longitude")
+	}
+
+	deviceInfo, err := s.Device.FetchDeviceInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rule, devices, err := s.rows(schedule, deviceInfo.UDN, time.Now().In(s.location()))
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := Fetch(ctx, s.Device)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if schedule.At.IsSun() || schedule.Until.IsSun() {
+		if err := db.SetLocation(s.Latitude, s.Longitude); err != nil {
+			return 0, err
+		}
+	}
+
+	ruleID, err := db.AddRule(rule, devices, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return ruleID, db.Store(ctx, s.Device)
+}
+
+// Remove deletes the rule from the device
+func (s *Scheduler) Remove(ctx context.Context, ruleID int) error {
+	db, err := Fetch(ctx, s.Device)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.RemoveRule(ruleID); err != nil {
+		return err
+	}
+
+	return db.Store(ctx, s.Device)
+}
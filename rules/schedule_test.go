@@ -0,0 +1,162 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rules
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/savaki/go.wemo"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	Convey("Given schedule strings", t, func() {
+		Convey("Then I expect days to be parsed", func() {
+			days, err := ParseDays("weekdays")
+			So(err, ShouldBeNil)
+			So(days, ShouldEqual, Weekdays)
+
+			days, err = ParseDays("mon,wed,fri")
+			So(err, ShouldBeNil)
+			So(days, ShouldEqual, Monday|Wednesday|Friday)
+			So(days.String(), ShouldEqual, "mon,wed,fri")
+
+			_, err = ParseDays("someday")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Then I expect times to be parsed", func() {
+			at, err := ParseAt("07:30")
+			So(err, ShouldBeNil)
+			So(at, ShouldResemble, Clock(7, 30))
+
+			at, err = ParseAt("sunset-15m")
+			So(err, ShouldBeNil)
+			So(at, ShouldResemble, Sunset.Offset(-15*time.Minute))
+			So(at.String(), ShouldEqual, "sunset-15m0s")
+
+			_, err = ParseAt("25:00")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestScheduleRows(t *testing.T) {
+	Convey("Given a scheduler in San Francisco", t, func() {
+		loc, _ := time.LoadLocation("America/Los_Angeles")
+		now := time.Date(2014, time.June, 21, 9, 0, 0, 0, loc)
+		scheduler := &Scheduler{Latitude: 37.7749, Longitude: -122.4194, Location: loc}
+
+		Convey("When I schedule on at sunset-15m on weekdays", func() {
+			rule, devices, err := scheduler.rows(Schedule{Days: Weekdays, At: Sunset.Offset(-15 * time.Minute), Action: On}, "uuid:a", now)
+
+			Convey("Then I expect a time interval rule with a row per weekday", func() {
+				So(err, ShouldBeNil)
+				So(rule.Type, ShouldEqual, TypeTimeInterval)
+				So(len(devices), ShouldEqual, 5)
+				So(devices[0].DayID, ShouldEqual, 2)
+				So(devices[4].DayID, ShouldEqual, 6)
+			})
+
+			Convey("Then I expect the sunset offset to be recorded", func() {
+				So(devices[0].Type, ShouldEqual, sunsetType)
+				So(devices[0].OnModeOffset, ShouldEqual, -900)
+				So(devices[0].StartAction, ShouldEqual, 1)
+				So(devices[0].EndAction, ShouldEqual, -1)
+				// sunset is around 20:35, less 15 minutes
+				So(devices[0].StartTime, ShouldBeBetween, 20*3600+15*60, 20*3600+25*60)
+			})
+		})
+
+		Convey("When I schedule off 30 minutes after on", func() {
+			rule, devices, err := scheduler.rows(Schedule{Type: Countdown, Delay: 30 * time.Minute, Action: Off}, "uuid:a", now)
+
+			Convey("Then I expect a single daily countdown row", func() {
+				So(err, ShouldBeNil)
+				So(rule.Type, ShouldEqual, TypeCountdown)
+				So(len(devices), ShouldEqual, 1)
+				So(devices[0].DayID, ShouldEqual, -1)
+				So(devices[0].CountdownTime, ShouldEqual, 1800)
+				So(devices[0].EndAction, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I schedule away mode from 19:00 to 01:00", func() {
+			rule, devices, err := scheduler.rows(Schedule{Type: Away, Days: Weekends, At: Clock(19, 0), Until: Clock(1, 0)}, "uuid:a", now)
+
+			Convey("Then I expect the duration to wrap past midnight", func() {
+				So(err, ShouldBeNil)
+				So(rule.Type, ShouldEqual, TypeAwayMode)
+				So(len(devices), ShouldEqual, 2)
+				So(devices[0].RuleDuration, ShouldEqual, 6*3600)
+			})
+		})
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	Convey("Given a device", t, func() {
+		server := httptest.NewServer(&rulesDevice{version: "3"})
+		defer server.Close()
+
+		device := &wemo.Device{Host: strings.TrimPrefix(server.URL, "http://")}
+		scheduler := Schedules(device)
+		ctx := context.Background()
+
+		Convey("When I add a schedule", func() {
+			ruleID, err := scheduler.Add(ctx, Schedule{Days: Daily, At: Clock(7, 0), Action: On})
+			So(err, ShouldBeNil)
+
+			Convey("Then I expect to list it", func() {
+				scheduled, err := scheduler.List(ctx)
+				So(err, ShouldBeNil)
+				So(len(scheduled), ShouldEqual, 1)
+				So(scheduled[0].Name, ShouldEqual, "on at 07:00")
+				So(len(scheduled[0].Devices), ShouldEqual, 7)
+			})
+
+			Convey("And I remove it", func() {
+				So(scheduler.Remove(ctx, ruleID), ShouldBeNil)
+
+				Convey("Then I expect no schedules", func() {
+					scheduled, _ := scheduler.List(ctx)
+					So(len(scheduled), ShouldEqual, 0)
+				})
+			})
+
+			Convey("And I remove an id that doesn't exist", func() {
+				version := server.Config.Handler.(*rulesDevice).version
+				err := scheduler.Remove(ctx, ruleID+1)
+
+				Convey("Then I expect an error and nothing to be stored", func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, fmt.Sprintf("no schedule with id %d", ruleID+1))
+					So(server.Config.Handler.(*rulesDevice).version, ShouldEqual, version)
+				})
+			})
+		})
+
+		Convey("When I add a sunset schedule without a location", func() {
+			_, err := scheduler.Add(ctx, Schedule{Days: Daily, At: Sunset, Action: On})
+
+			Convey("Then I expect an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,62 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rules
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	julianUnixEpoch = 2440587.5
+	julian2000      = 2451545.0
+)
+
+func radians(degrees float64) float64 { return degrees * math.Pi / 180 }
+func degrees(radians float64) float64 { return radians * 180 / math.Pi }
+
+func toJulian(t time.Time) float64 {
+	return float64(t.Unix())/86400 + julianUnixEpoch
+}
+
+func fromJulian(j float64) time.Time {
+	return time.Unix(int64(math.Floor((j-julianUnixEpoch)*86400+0.5)), 0)
+}
+
+// sunriseSunset computes sunrise and sunset on the date t falls on, using the
+// sunrise equation.  It's accurate to within a minute or two, which is all
+// the firmware's own calculation manages anyway
+func sunriseSunset(t time.Time, latitude, longitude float64) (time.Time, time.Time, error) {
+	noon := time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, t.Location())
+
+	n := math.Floor(toJulian(noon) - julian2000 + 0.0008 + 0.5)
+	meanSolarNoon := n - longitude/360
+	anomaly := math.Mod(357.5291+0.98560028*meanSolarNoon, 360)
+	center := 1.9148*math.Sin(radians(anomaly)) + 0.02*math.Sin(radians(2*anomaly)) + 0.0003*math.Sin(radians(3*anomaly))
+	ecliptic := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := julian2000 + meanSolarNoon + 0.0053*math.Sin(radians(anomaly)) - 0.0069*math.Sin(radians(2*ecliptic))
+
+	declination := math.Asin(math.Sin(radians(ecliptic)) * math.Sin(radians(23.44)))
+	cosHourAngle := (math.Sin(radians(-0.833)) - math.Sin(radians(latitude))*math.Sin(declination)) /
+		(math.Cos(radians(latitude)) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, fmt.Errorf("the sun doesn't rise and set at %v, %v on %s", latitude, longitude, t.Format("2006-01-02"))
+	}
+	hourAngle := degrees(math.Acos(cosHourAngle))
+
+	sunrise := fromJulian(transit - hourAngle/360).In(t.Location())
+	sunset := fromJulian(transit + hourAngle/360).In(t.Location())
+	return sunrise, sunset, nil
+}
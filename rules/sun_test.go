@@ -0,0 +1,46 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rules
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestSunriseSunset(t *testing.T) {
+	Convey("Given San Francisco on the 2014 summer solstice", t, func() {
+		loc, _ := time.LoadLocation("America/Los_Angeles")
+		date := time.Date(2014, time.June, 21, 0, 0, 0, 0, loc)
+
+		Convey("When I call #sunriseSunset", func() {
+			sunrise, sunset, err := sunriseSunset(date, 37.7749, -122.4194)
+
+			Convey("Then I expect sunrise around 5:48am and sunset around 8:35pm", func() {
+				So(err, ShouldBeNil)
+				So(sunrise.Sub(time.Date(2014, time.June, 21, 5, 48, 0, 0, loc)), ShouldBeBetween, -3*time.Minute, 3*time.Minute)
+				So(sunset.Sub(time.Date(2014, time.June, 21, 20, 35, 0, 0, loc)), ShouldBeBetween, -3*time.Minute, 3*time.Minute)
+			})
+		})
+	})
+
+	Convey("Given the north pole in June", t, func() {
+		date := time.Date(2014, time.June, 21, 0, 0, 0, 0, time.UTC)
+
+		Convey("Then I expect an error", func() {
+			_, _, err := sunriseSunset(date, 89.9, 0)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
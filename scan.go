@@ -14,12 +14,10 @@
 package wemo
 
 import (
+	"code.google.com/p/go.net/context"
 	"fmt"
 	"log"
 	"net"
-	"net/url"
-	"strings"
-	"time"
 )
 
 const (
@@ -28,76 +26,87 @@ const (
 	LOCATION       = "LOCATION: "
 )
 
-// scan the multicast
-func (self *Wemo) scan(urn string, timeout time.Duration) ([]*url.URL, error) {
+// ssdpAddr is where searches are sent
+var ssdpAddr = SSDP_BROADCAST
+
+// scan sends an M-SEARCH for each urn from ipAddr and reports each reply from
+// a Belkin device until ctx is done
+func (self *Wemo) scan(ctx context.Context, ipAddr string, urns []string, responses chan<- DiscoveredDevice) error {
 	// open a udp port for us to receive multicast messages
-	udpAddr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:0", self.ipAddr))
+	udpAddr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:0", ipAddr))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	udpConn, err := net.ListenUDP("udp", udpAddr)
+	udpConn, err := net.ListenUDP("udp4", udpAddr)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer udpConn.Close()
 
-	//send the
-	mAddr, err := net.ResolveUDPAddr("udp", SSDP_BROADCAST)
+	mAddr, err := net.ResolveUDPAddr("udp4", ssdpAddr)
 	if err != nil {
-		return nil, err
-	}
-
-	if self.Debug {
-		log.Printf("Found multi-cast address %v", mAddr)
+		return err
 	}
-	packet := fmt.Sprintf(M_SEARCH, urn)
 
-	if self.Debug {
-		log.Printf("Writing discovery packet")
-	}
-	_, err = udpConn.WriteTo([]byte(packet), mAddr)
-	if err != nil {
-		return nil, err
+	for _, urn := range urns {
+		if self.Debug {
+			log.Printf("Writing discovery packet for %s from %s\n", urn, udpConn.LocalAddr())
+		}
+		if _, err := udpConn.WriteTo([]byte(fmt.Sprintf(M_SEARCH, urn)), mAddr); err != nil {
+			return err
+		}
 	}
 
-	if self.Debug {
-		log.Printf("Setting read deadline")
-	}
-	err = udpConn.SetReadDeadline(time.Now().Add(timeout))
-	if err != nil {
-		return nil, err
-	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			udpConn.Close()
+		case <-done:
+		}
+	}()
 
-	locations := make(map[string]*url.URL)
+	buffer := make([]byte, 2048)
 	for {
-		buffer := make([]byte, 2048)
 		n, err := udpConn.Read(buffer)
 		if err != nil {
-			break
-		}
-		read := string(buffer[:n])
-		lines := strings.Split(read, "\n")
-		for _, line := range lines {
-			if strings.HasPrefix(line, LOCATION) {
-				temp := strings.TrimSpace(line[len(LOCATION):])
-				u, err := url.Parse(temp)
-				if err != nil {
-					return nil, err
-				}
-				locations[temp] = u
+			if ctx.Err() != nil {
+				return nil
 			}
+			return err
 		}
 
 		if self.Debug {
 			log.Printf("Read : %v\n", string(buffer[:n]))
 		}
-	}
 
-	var results []*url.URL
-	for _, value := range locations {
-		results = append(results, value)
-	}
+		response, err := parseSSDPResponse(buffer[:n])
+		if err != nil {
+			if self.Debug {
+				log.Printf("Skipping malformed response => %s\n", err)
+			}
+			continue
+		}
 
-	return results, nil
+		host, ok := response.Host()
+		if !ok {
+			continue
+		}
+
+		found := DiscoveredDevice{
+			Device:   &Device{Host: host},
+			URN:      response.ST,
+			USN:      response.USN,
+			Location: response.Location,
+			Response: response,
+		}
+
+		select {
+		case responses <- found:
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
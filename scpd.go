@@ -0,0 +1,108 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"encoding/xml"
+	"github.com/savaki/httpctx"
+)
+
+type Argument struct {
+	Name                 string `xml:"name" json:"name"`
+	Direction            string `xml:"direction" json:"direction"`
+	RelatedStateVariable string `xml:"relatedStateVariable" json:"related-state-variable"`
+}
+
+type Action struct {
+	Name      string     `xml:"name" json:"name"`
+	Arguments []Argument `xml:"argumentList>argument" json:"arguments,omitempty"`
+}
+
+type StateVariable struct {
+	Name          string   `xml:"name" json:"name"`
+	DataType      string   `xml:"dataType" json:"data-type"`
+	DefaultValue  string   `xml:"defaultValue" json:"default-value,omitempty"`
+	SendEvents    string   `xml:"sendEvents,attr" json:"send-events,omitempty"`
+	AllowedValues []string `xml:"allowedValueList>allowedValue" json:"allowed-values,omitempty"`
+}
+
+// ServiceDescription is the parsed SCPD document of a single service
+type ServiceDescription struct {
+	Service        Service         `json:"service"`
+	Actions        []Action        `xml:"actionList>action" json:"actions"`
+	StateVariables []StateVariable `xml:"serviceStateTable>stateVariable" json:"state-variables"`
+}
+
+// Action returns the named action, if the service supports it
+func (s *ServiceDescription) Action(name string) (Action, bool) {
+	for _, action := range s.Actions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+func unmarshalServiceDescription(data []byte) (*ServiceDescription, error) {
+	description := &ServiceDescription{}
+	err := xml.Unmarshal(data, description)
+	if err != nil {
+		return nil, err
+	}
+
+	return description, nil
+}
+
+// DescribeService fetches and parses the SCPD for the given service
+func (d *Device) DescribeService(ctx context.Context, service Service) (*ServiceDescription, error) {
+	uri, err := d.resolve(service.SCPDURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = httpctx.NewClient().Get(ctx, uri, nil, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	description, err := unmarshalServiceDescription(data)
+	if err != nil {
+		return nil, err
+	}
+
+	description.Service = service
+	return description, nil
+}
+
+// DescribeServices fetches the SCPD of every service listed in setup.xml so
+// callers can see which actions and state variables the firmware supports
+func (d *Device) DescribeServices(ctx context.Context) ([]*ServiceDescription, error) {
+	deviceInfo, err := d.FetchDeviceInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var descriptions []*ServiceDescription
+	for _, service := range deviceInfo.Services {
+		description, err := d.DescribeService(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+		descriptions = append(descriptions, description)
+	}
+
+	return descriptions, nil
+}
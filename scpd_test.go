@@ -0,0 +1,92 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestParseServiceDescription(t *testing.T) {
+	Convey("Given an SCPD document", t, func() {
+		data := []byte(`<?xml version="1.0"?>
+<scpd xmlns="urn:Belkin:service-1-0">
+  <specVersion>
+    <major>1</major>
+    <minor>0</minor>
+  </specVersion>
+  <actionList>
+    <action>
+      <name>SetBinaryState</name>
+      <argumentList>
+        <argument>
+          <retval />
+          <name>BinaryState</name>
+          <relatedStateVariable>BinaryState</relatedStateVariable>
+          <direction>in</direction>
+        </argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetFriendlyName</name>
+      <argumentList>
+        <argument>
+          <retval />
+          <name>FriendlyName</name>
+          <relatedStateVariable>FriendlyName</relatedStateVariable>
+          <direction>out</direction>
+        </argument>
+      </argumentList>
+    </action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="yes">
+      <name>BinaryState</name>
+      <dataType>Boolean</dataType>
+      <defaultValue>0</defaultValue>
+    </stateVariable>
+    <stateVariable sendEvents="no">
+      <name>FriendlyName</name>
+      <dataType>string</dataType>
+    </stateVariable>
+  </serviceStateTable>
+</scpd>`)
+
+		Convey("When I call #unmarshalServiceDescription", func() {
+			description, err := unmarshalServiceDescription(data)
+
+			Convey("Then I expect no errors", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then I expect the actions to be set", func() {
+				So(len(description.Actions), ShouldEqual, 2)
+
+				action, ok := description.Action("SetBinaryState")
+				So(ok, ShouldBeTrue)
+				So(action.Arguments[0].Name, ShouldEqual, "BinaryState")
+				So(action.Arguments[0].Direction, ShouldEqual, "in")
+				So(action.Arguments[0].RelatedStateVariable, ShouldEqual, "BinaryState")
+			})
+
+			Convey("Then I expect the state variables to be set", func() {
+				So(len(description.StateVariables), ShouldEqual, 2)
+				So(description.StateVariables[0].Name, ShouldEqual, "BinaryState")
+				So(description.StateVariables[0].DataType, ShouldEqual, "Boolean")
+				So(description.StateVariables[0].DefaultValue, ShouldEqual, "0")
+				So(description.StateVariables[0].SendEvents, ShouldEqual, "yes")
+			})
+		})
+	})
+}
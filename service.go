@@ -0,0 +1,123 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"net/url"
+)
+
+// service types advertised in the serviceList of setup.xml
+const (
+	ServiceWiFiSetup      = "urn:Belkin:service:WiFiSetup:1"
+	ServiceTimeSync       = "urn:Belkin:service:timesync:1"
+	ServiceBasicEvent     = "urn:Belkin:service:basicevent:1"
+	ServiceFirmwareUpdate = "urn:Belkin:service:firmwareupdate:1"
+	ServiceRules          = "urn:Belkin:service:rules:1"
+	ServiceMetaInfo       = "urn:Belkin:service:metainfo:1"
+	ServiceRemoteAccess   = "urn:Belkin:service:remoteaccess:1"
+	ServiceDeviceInfo     = "urn:Belkin:service:deviceinfo:1"
+	ServiceDeviceEvent    = "urn:Belkin:service:deviceevent:1"
+)
+
+type Service struct {
+	ServiceType string `xml:"serviceType" json:"service-type"`
+	ServiceId   string `xml:"serviceId" json:"service-id"`
+	ControlURL  string `xml:"controlURL" json:"control-url"`
+	EventSubURL string `xml:"eventSubURL" json:"event-sub-url"`
+	SCPDURL     string `xml:"SCPDURL" json:"scpd-url"`
+}
+
+func findService(services []Service, serviceType string) (Service, bool) {
+	for _, service := range services {
+		if service.ServiceType == serviceType {
+			return service, true
+		}
+	}
+	return Service{}, false
+}
+
+// service returns the named service from the device's serviceList, fetching
+// setup.xml the first time it's needed
+func (d *Device) service(ctx context.Context, serviceType string) (Service, error) {
+	d.mutex.Lock()
+	services := d.services
+	d.mutex.Unlock()
+
+	if services == nil {
+		if _, err := d.FetchDeviceInfo(ctx); err != nil {
+			return Service{}, err
+		}
+
+		d.mutex.Lock()
+		services = d.services
+		d.mutex.Unlock()
+	}
+
+	if service, ok := findService(services, serviceType); ok {
+		return service, nil
+	}
+
+	return Service{}, fmt.Errorf("device %s does not support service %s", d.CurrentHost(), serviceType)
+}
+
+// resolve returns the absolute url of a path found in setup.xml
+func (d *Device) resolve(path string) (string, error) {
+	base, err := url.Parse(fmt.Sprintf("http://%s/", d.CurrentHost()))
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// Call invokes action on the device service identified by serviceType.  args
+// may be nil, a map[string]string or a struct whose fields are encoded in order
+// as the action's arguments.  The response arguments are available as a map via
+// Response.Args or can be decoded into a struct with Response.Unmarshal
+func (d *Device) Call(ctx context.Context, serviceType, action string, args interface{}) (*Response, error) {
+	service, err := d.service(ctx, serviceType)
+	if err != nil {
+		return nil, err
+	}
+
+	host := d.CurrentHost()
+	uri, err := d.resolve(service.ControlURL)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := post(ctx, uri, serviceType, action, args)
+	if !isConnectionRefused(err) {
+		return response, err
+	}
+
+	// the device may have moved to another port; retry once it's found
+	if _, err := d.relocate(ctx, host, err); err != nil {
+		return nil, err
+	}
+
+	uri, err = d.resolve(service.ControlURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return post(ctx, uri, serviceType, action, args)
+}
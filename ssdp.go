@@ -0,0 +1,84 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SSDPResponse is a reply to an M-SEARCH
+type SSDPResponse struct {
+	ST           string
+	USN          string
+	Server       string
+	CacheControl string
+	MaxAge       time.Duration
+	Location     *url.URL
+
+	// UserAgent is the Belkin X-User-Agent extension e.g. redsonic
+	UserAgent string
+
+	// Header holds every header including ones not broken out above
+	Header http.Header
+}
+
+// Host returns the device host if the response came from a Belkin device
+func (r *SSDPResponse) Host() (string, bool) {
+	if r.Location == nil {
+		return "", false
+	}
+	if matches := belkinRE.FindStringSubmatch(r.Location.String()); len(matches) == 2 {
+		return matches[1], true
+	}
+	return "", false
+}
+
+// parseSSDPResponse parses an M-SEARCH reply.  Headers are matched case
+// insensitively and lines may end with either CRLF or LF
+func parseSSDPResponse(data []byte) (*SSDPResponse, error) {
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected ssdp status, " + response.Status)
+	}
+
+	result := &SSDPResponse{
+		ST:           response.Header.Get("ST"),
+		USN:          response.Header.Get("USN"),
+		Server:       response.Header.Get("SERVER"),
+		CacheControl: response.Header.Get("CACHE-CONTROL"),
+		MaxAge:       parseMaxAge(response.Header.Get("CACHE-CONTROL")),
+		UserAgent:    response.Header.Get("X-User-Agent"),
+		Header:       response.Header,
+	}
+
+	location := response.Header.Get("LOCATION")
+	if location == "" {
+		return nil, errors.New("ssdp response has no LOCATION")
+	}
+	if result.Location, err = url.Parse(location); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
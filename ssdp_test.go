@@ -0,0 +1,68 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestParseSSDPResponse(t *testing.T) {
+	Convey("Given a response with mixed case headers and bare newlines", t, func() {
+		data := "HTTP/1.1 200 OK\n" +
+			"Cache-Control: max-age=86400\n" +
+			"DATE: Tue, 16 Dec 2014 04:15:24 GMT\n" +
+			"EXT:\n" +
+			"location: http://10.0.1.32:49153/setup.xml\n" +
+			"OPT: \"http://schemas.upnp.org/upnp/1/0/\"; ns=01\n" +
+			"01-NLS: 905bfa3c-1dd2-11b2-8928-fd8aebaf491c\n" +
+			"SERVER: Unspecified, UPnP/1.0, Unspecified\n" +
+			"X-User-Agent: redsonic\n" +
+			"st: urn:Belkin:device:controllee:1\n" +
+			"Usn: uuid:Socket-1_0-221326K0101234::urn:Belkin:device:controllee:1\n" +
+			"\n"
+
+		Convey("When I call parseSSDPResponse", func() {
+			response, err := parseSSDPResponse([]byte(data))
+
+			Convey("Then I expect every header to be found", func() {
+				So(err, ShouldBeNil)
+				So(response.ST, ShouldEqual, "urn:Belkin:device:controllee:1")
+				So(response.USN, ShouldEqual, "uuid:Socket-1_0-221326K0101234::urn:Belkin:device:controllee:1")
+				So(response.Server, ShouldEqual, "Unspecified, UPnP/1.0, Unspecified")
+				So(response.MaxAge, ShouldEqual, 24*time.Hour)
+				So(response.UserAgent, ShouldEqual, "redsonic")
+				So(response.Header.Get("01-NLS"), ShouldEqual, "905bfa3c-1dd2-11b2-8928-fd8aebaf491c")
+			})
+
+			Convey("Then I expect the host to be found", func() {
+				host, ok := response.Host()
+				So(ok, ShouldBeTrue)
+				So(host, ShouldEqual, "10.0.1.32:49153")
+			})
+		})
+	})
+
+	Convey("Given malformed responses", t, func() {
+		for _, data := range []string{
+			"garbage",
+			"HTTP/1.1 200 OK\r\nST: upnp:rootdevice\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nLOCATION: http://[::1\r\n\r\n",
+		} {
+			_, err := parseSSDPResponse([]byte(data))
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
@@ -0,0 +1,417 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultSubscriptionTimeout = 300 * time.Second
+
+var (
+	// how long to wait before trying again when a renewal fails
+	retryInterval = 15 * time.Second
+
+	errSubscriptionExpired = errors.New("subscription no longer recognized by device")
+
+	errNoCallbackAddress = errors.New("no callback address; use NewByIp or NewByInterface, or call NewSubscriber with an address reachable from the devices")
+)
+
+// Subscriber receives GENA events from devices.  It runs a small http server
+// that devices NOTIFY and keeps every subscription renewed until it's closed
+type Subscriber struct {
+	// Timeout requested for each subscription; defaults to DefaultSubscriptionTimeout
+	Timeout time.Duration
+
+	listener net.Listener
+	events   chan Event
+	done     chan struct{}
+
+	// handlers tracks NOTIFY requests in progress so events can be closed
+	// once the last one finishes
+	handlers sync.WaitGroup
+
+	mutex         sync.Mutex
+	nextId        int
+	subscriptions map[string]*Subscription
+}
+
+type Subscription struct {
+	Device      *Device
+	ServiceType string
+
+	subscriber  *Subscriber
+	path        string
+	eventSubURL string
+	done        chan struct{}
+
+	mutex   sync.Mutex
+	sid     string
+	timeout time.Duration
+}
+
+// NewSubscriber listens for events on the specified address.  The address must
+// be reachable from the devices e.g. 10.0.1.5 or 10.0.1.5:8989
+func NewSubscriber(addr string) (*Subscriber, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "0")
+	}
+
+	// devices can't reach a callback on 0.0.0.0
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		return nil, errNoCallbackAddress
+	}
+
+	listener, err := net.Listen("tcp4", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Subscriber{
+		listener:      listener,
+		events:        make(chan Event, 64),
+		done:          make(chan struct{}),
+		subscriptions: map[string]*Subscription{},
+	}
+	go http.Serve(listener, s)
+
+	return s, nil
+}
+
+// NewSubscriber listens for events on the address discovery is bound to.
+// NewAuto has no single address devices can reach, so use the package level
+// NewSubscriber with an address on the devices' subnet instead
+func (self *Wemo) NewSubscriber() (*Subscriber, error) {
+	if self.auto || self.ipAddr == "" {
+		return nil, errNoCallbackAddress
+	}
+	return NewSubscriber(self.ipAddr)
+}
+
+// Events returns the channel events are delivered on.  It's closed once the
+// Subscriber is closed.  Events are dropped rather than holding up the device
+// when the channel is full, so keep reading
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Subscribe asks the device to send events for the specified service
+func (s *Subscriber) Subscribe(ctx context.Context, device *Device, serviceType string) (*Subscription, error) {
+	service, err := device.service(ctx, serviceType)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.nextId++
+	sub := &Subscription{
+		Device:      device,
+		ServiceType: serviceType,
+		subscriber:  s,
+		path:        fmt.Sprintf("/event/%d", s.nextId),
+		eventSubURL: service.EventSubURL,
+		done:        make(chan struct{}),
+	}
+	// register before subscribing; devices send the initial NOTIFY right away
+	s.subscriptions[sub.path] = sub
+	s.mutex.Unlock()
+
+	if err := sub.subscribe(ctx); err != nil {
+		s.remove(sub)
+		return nil, err
+	}
+
+	go sub.renewLoop()
+	return sub, nil
+}
+
+// Unsubscribe cancels the subscription with the device and stops renewing it
+func (s *Subscriber) Unsubscribe(ctx context.Context, sub *Subscription) error {
+	if !s.remove(sub) {
+		return nil
+	}
+	close(sub.done)
+
+	return sub.unsubscribe(ctx)
+}
+
+// Close unsubscribes everything and stops the callback server
+func (s *Subscriber) Close() error {
+	s.mutex.Lock()
+	select {
+	case <-s.done:
+		s.mutex.Unlock()
+		return nil
+	default:
+	}
+	close(s.done)
+
+	var subscriptions []*Subscription
+	for _, sub := range s.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
+	s.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, sub := range subscriptions {
+		s.Unsubscribe(ctx, sub)
+	}
+
+	err := s.listener.Close()
+	s.handlers.Wait()
+	close(s.events)
+
+	return err
+}
+
+func (s *Subscriber) remove(sub *Subscription) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.subscriptions[sub.path]; !ok {
+		return false
+	}
+	delete(s.subscriptions, sub.path)
+	return true
+}
+
+func (s *Subscriber) callbackURL(sub *Subscription) string {
+	return fmt.Sprintf("http://%s%s", s.listener.Addr().String(), sub.path)
+}
+
+// ServeHTTP handles the NOTIFY requests sent by devices
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "NOTIFY" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mutex.Lock()
+	select {
+	case <-s.done:
+		s.mutex.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	default:
+	}
+	sub, ok := s.subscriptions[req.URL.Path]
+	if ok {
+		s.handlers.Add(1)
+	}
+	s.mutex.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	defer s.handlers.Done()
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	properties, err := unmarshalPropertySet(data)
+	if err != nil {
+		sub.Device.printf("unable to parse NOTIFY from %s => %s\n", sub.Device.CurrentHost(), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+
+	seq, _ := strconv.Atoi(req.Header.Get("SEQ"))
+	now := time.Now()
+	for _, p := range properties {
+		event := Event{
+			Device:      sub.Device,
+			ServiceType: sub.ServiceType,
+			SID:         req.Header.Get("SID"),
+			Seq:         seq,
+			Name:        p.Name,
+			Value:       p.Value,
+			Time:        now,
+		}
+
+		select {
+		case s.events <- event:
+		default:
+			sub.Device.printf("dropped %s event from %s; events are not being read\n", p.Name, sub.Device.CurrentHost())
+		}
+	}
+}
+
+// SID returns the subscription id currently assigned by the device
+func (sub *Subscription) SID() string {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	return sub.sid
+}
+
+func (sub *Subscription) request(ctx context.Context, method string, header map[string]string) (*http.Response, error) {
+	uri, err := sub.Device.resolve(sub.eventSubURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Close = true
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	response, err := do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return response, nil
+	case http.StatusPreconditionFailed:
+		return nil, errSubscriptionExpired
+	default:
+		return nil, fmt.Errorf("%s %s returned status code => %d", method, uri, response.StatusCode)
+	}
+}
+
+func (sub *Subscription) requestedTimeout() string {
+	timeout := sub.subscriber.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSubscriptionTimeout
+	}
+	return fmt.Sprintf("Second-%d", int(timeout/time.Second))
+}
+
+func (sub *Subscription) update(response *http.Response) {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+
+	if sid := response.Header.Get("SID"); sid != "" {
+		sub.sid = sid
+	}
+	sub.timeout = parseTimeout(response.Header.Get("TIMEOUT"))
+}
+
+func (sub *Subscription) subscribe(ctx context.Context) error {
+	response, err := sub.request(ctx, "SUBSCRIBE", map[string]string{
+		"CALLBACK": fmt.Sprintf("<%s>", sub.subscriber.callbackURL(sub)),
+		"NT":       "upnp:event",
+		"TIMEOUT":  sub.requestedTimeout(),
+	})
+	if err != nil {
+		return err
+	}
+
+	sub.update(response)
+	return nil
+}
+
+func (sub *Subscription) renew(ctx context.Context) error {
+	response, err := sub.request(ctx, "SUBSCRIBE", map[string]string{
+		"SID":     sub.SID(),
+		"TIMEOUT": sub.requestedTimeout(),
+	})
+	if err != nil {
+		return err
+	}
+
+	sub.update(response)
+	return nil
+}
+
+func (sub *Subscription) unsubscribe(ctx context.Context) error {
+	_, err := sub.request(ctx, "UNSUBSCRIBE", map[string]string{
+		"SID": sub.SID(),
+	})
+	return err
+}
+
+// renewLoop renews the subscription before it expires.  When the device no
+// longer recognizes the SID, typically because it rebooted, a fresh
+// subscription is made instead
+func (sub *Subscription) renewLoop() {
+	wait := renewAfter(sub.currentTimeout())
+
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-sub.done:
+			timer.Stop()
+			return
+		case <-sub.subscriber.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := sub.renew(ctx)
+		if err != nil {
+			sub.Device.printf("unable to renew subscription to %s on %s => %s\n", sub.ServiceType, sub.Device.CurrentHost(), err)
+			err = sub.subscribe(ctx)
+		}
+		cancel()
+
+		if err != nil {
+			sub.Device.printf("unable to subscribe to %s on %s => %s\n", sub.ServiceType, sub.Device.CurrentHost(), err)
+			wait = retryInterval
+			continue
+		}
+		wait = renewAfter(sub.currentTimeout())
+	}
+}
+
+func (sub *Subscription) currentTimeout() time.Duration {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	return sub.timeout
+}
+
+// renewAfter leaves a margin so the renewal reaches the device before the
+// subscription lapses
+func renewAfter(timeout time.Duration) time.Duration {
+	return timeout * 4 / 5
+}
+
+// parseTimeout reads a TIMEOUT header e.g. Second-1800
+func parseTimeout(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(strings.ToLower(value), "second-") {
+		return DefaultSubscriptionTimeout
+	}
+
+	seconds, err := strconv.Atoi(value[len("second-"):])
+	if err != nil || seconds <= 0 {
+		return DefaultSubscriptionTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,238 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"bytes"
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventDevice is a fake device that accepts subscriptions and immediately
+// NOTIFYs the subscriber
+type eventDevice struct {
+	mutex      sync.Mutex
+	subscribes int
+	renewals   int
+	expired    bool
+}
+
+func (e *eventDevice) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.URL.Path == "/setup.xml":
+		w.Write([]byte(testSetupXML))
+
+	case req.Method == "SUBSCRIBE" && req.Header.Get("CALLBACK") != "":
+		e.mutex.Lock()
+		e.subscribes++
+		e.mutex.Unlock()
+
+		callback := strings.Trim(req.Header.Get("CALLBACK"), "<>")
+		w.Header().Set("SID", "uuid:sid-1")
+		w.Header().Set("TIMEOUT", "Second-1")
+		w.WriteHeader(http.StatusOK)
+
+		go func() {
+			body := `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><BinaryState>8|1414583000|0|0</BinaryState></e:property></e:propertyset>`
+			notify, _ := http.NewRequest("NOTIFY", callback, bytes.NewReader([]byte(body)))
+			notify.Header.Set("NT", "upnp:event")
+			notify.Header.Set("NTS", "upnp:propchange")
+			notify.Header.Set("SID", "uuid:sid-1")
+			notify.Header.Set("SEQ", "0")
+			if response, err := http.DefaultClient.Do(notify); err == nil {
+				response.Body.Close()
+			}
+		}()
+
+	case req.Method == "SUBSCRIBE":
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		e.renewals++
+		if e.expired {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.Header().Set("TIMEOUT", "Second-1")
+		w.WriteHeader(http.StatusOK)
+
+	case req.Method == "UNSUBSCRIBE":
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (e *eventDevice) counts() (int, int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.subscribes, e.renewals
+}
+
+func TestParseTimeout(t *testing.T) {
+	Convey("Given TIMEOUT headers", t, func() {
+		Convey("Then I expect seconds to be parsed", func() {
+			So(parseTimeout("Second-1800"), ShouldEqual, 1800*time.Second)
+		})
+
+		Convey("Then I expect infinite or garbage to use the default", func() {
+			So(parseTimeout("infinite"), ShouldEqual, DefaultSubscriptionTimeout)
+			So(parseTimeout(""), ShouldEqual, DefaultSubscriptionTimeout)
+		})
+	})
+}
+
+func TestUnmarshalPropertySet(t *testing.T) {
+	Convey("Given a NOTIFY body", t, func() {
+		data := []byte(`<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><BinaryState>1</BinaryState></e:property></e:propertyset>`)
+
+		Convey("When I call #unmarshalPropertySet", func() {
+			properties, err := unmarshalPropertySet(data)
+
+			Convey("Then I expect the property to be parsed", func() {
+				So(err, ShouldBeNil)
+				So(len(properties), ShouldEqual, 1)
+				So(properties[0].Name, ShouldEqual, "BinaryState")
+				So(properties[0].Value, ShouldEqual, "1")
+			})
+		})
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	Convey("Given a device that supports events", t, func() {
+		fake := &eventDevice{}
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		device := &Device{Host: strings.TrimPrefix(server.URL, "http://")}
+		subscriber, err := NewSubscriber("127.0.0.1")
+		So(err, ShouldBeNil)
+		defer subscriber.Close()
+
+		Convey("When I subscribe to basicevent", func() {
+			sub, err := subscriber.Subscribe(context.Background(), device, ServiceBasicEvent)
+			So(err, ShouldBeNil)
+
+			Convey("Then I expect the SID to be recorded", func() {
+				So(sub.SID(), ShouldEqual, "uuid:sid-1")
+			})
+
+			Convey("Then I expect to receive the initial event", func() {
+				select {
+				case event := <-subscriber.Events():
+					So(event.Device, ShouldEqual, device)
+					So(event.ServiceType, ShouldEqual, ServiceBasicEvent)
+					So(event.Name, ShouldEqual, "BinaryState")
+
+					state, ok := event.BinaryState()
+					So(ok, ShouldBeTrue)
+					So(state, ShouldEqual, 8)
+				case <-time.After(2 * time.Second):
+					So("timed out waiting for event", ShouldBeEmpty)
+				}
+			})
+
+			Convey("Then I expect the subscription to be renewed", func() {
+				time.Sleep(1200 * time.Millisecond)
+				_, renewals := fake.counts()
+				So(renewals, ShouldBeGreaterThan, 0)
+			})
+
+			Convey("Then I expect to resubscribe once the device forgets the SID", func() {
+				fake.mutex.Lock()
+				fake.expired = true
+				fake.mutex.Unlock()
+
+				time.Sleep(1200 * time.Millisecond)
+				subscribes, _ := fake.counts()
+				So(subscribes, ShouldBeGreaterThan, 1)
+			})
+		})
+	})
+}
+
+func TestSubscriberClose(t *testing.T) {
+	Convey("Given a subscriber whose events are not being read", t, func() {
+		fake := &eventDevice{}
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		device := &Device{Host: strings.TrimPrefix(server.URL, "http://")}
+		subscriber, err := NewSubscriber("127.0.0.1")
+		So(err, ShouldBeNil)
+		defer subscriber.Close()
+
+		sub, err := subscriber.Subscribe(context.Background(), device, ServiceBasicEvent)
+		So(err, ShouldBeNil)
+
+		Convey("When the device sends more events than the channel holds", func() {
+			client := &http.Client{Timeout: 2 * time.Second}
+			body := `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><BinaryState>1</BinaryState></e:property></e:propertyset>`
+
+			var failures int
+			for i := 0; i < 100; i++ {
+				notify, _ := http.NewRequest("NOTIFY", subscriber.callbackURL(sub), bytes.NewReader([]byte(body)))
+				response, err := client.Do(notify)
+				if err != nil {
+					failures++
+					continue
+				}
+				response.Body.Close()
+			}
+
+			Convey("Then I expect the device not to be held up", func() {
+				So(failures, ShouldEqual, 0)
+			})
+
+			Convey("Then I expect Events to be closed by Close", func() {
+				subscriber.Close()
+
+				count := 0
+				for range subscriber.Events() {
+					count++
+				}
+				So(count, ShouldEqual, cap(subscriber.events))
+			})
+		})
+	})
+}
+
+func TestWemoNewSubscriber(t *testing.T) {
+	Convey("Given auto discovery", t, func() {
+		_, err := NewAuto().NewSubscriber()
+
+		Convey("Then I expect an error rather than an unreachable callback", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestNewSubscriberUnspecified(t *testing.T) {
+	Convey("Given addresses devices can't call back", t, func() {
+		for _, addr := range []string{"", "0.0.0.0", "0.0.0.0:8989", ":8989", "::"} {
+			_, err := NewSubscriber(addr)
+
+			Convey("Then I expect "+addr+" to be rejected", func() {
+				So(err, ShouldEqual, errNoCallbackAddress)
+			})
+		}
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sweepWorkers bounds the number of concurrent probes
+	sweepWorkers = 64
+
+	// maxSweepHosts keeps a typo like /8 from probing millions of hosts
+	maxSweepHosts = 1 << 16
+)
+
+// sweepTimeout bounds each probe; hosts without a device usually time out
+var sweepTimeout = time.Second
+
+// hosts returns every usable address in cidr, skipping the network and
+// broadcast addresses of subnets larger than a /31
+func hosts(cidr string) ([]net.IP, error) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("only ipv4 subnets can be swept, %s", cidr)
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	size := 1 << uint(bits-ones)
+	if size > maxSweepHosts {
+		return nil, fmt.Errorf("subnet too large to sweep, %s", cidr)
+	}
+
+	base := ipNet.IP.To4()
+	start := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3])
+
+	var results []net.IP
+	for i := 0; i < size; i++ {
+		if size > 2 && (i == 0 || i == size-1) {
+			continue
+		}
+		n := start + uint32(i)
+		results = append(results, net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n)))
+	}
+	return results, nil
+}
+
+// isBelkinDevice reports whether setup.xml describes a WeMo device
+func isBelkinDevice(deviceInfo *DeviceInfo) bool {
+	return strings.HasPrefix(deviceInfo.DeviceType, "urn:Belkin:device:") && deviceInfo.UDN != ""
+}
+
+// Sweep probes every host in cidr on each device port for setup.xml.  Use it
+// where multicast is filtered and DiscoverAll finds nothing.  If ctx ends
+// first, the devices found so far are returned along with ctx.Err()
+func (self *Wemo) Sweep(ctx context.Context, cidr string) ([]*DeviceInfo, error) {
+	ips, err := hosts(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(chan string)
+	go func() {
+		defer close(candidates)
+		for _, ip := range ips {
+			for _, port := range devicePorts {
+				select {
+				case candidates <- net.JoinHostPort(ip.String(), strconv.Itoa(port)):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var mutex sync.Mutex
+	seen := map[string]bool{}
+	var results []*DeviceInfo
+
+	var wg sync.WaitGroup
+	for i := 0; i < sweepWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range candidates {
+				probe, cancel := context.WithTimeout(ctx, sweepTimeout)
+				deviceInfo, err := fetchDeviceInfo(probe, host)
+				cancel()
+				if err != nil || !isBelkinDevice(deviceInfo) {
+					continue
+				}
+
+				device := &Device{
+					Host:     host,
+					services: deviceInfo.Services,
+					udn:      deviceInfo.UDN,
+				}
+				deviceInfo.Device = device
+
+				mutex.Lock()
+				if !seen[deviceInfo.UDN] {
+					seen[deviceInfo.UDN] = true
+					results = append(results, deviceInfo)
+				}
+				mutex.Unlock()
+
+				if self.Debug {
+					log.Printf("found %s at %s\n", deviceInfo.DeviceType, host)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results, ctx.Err()
+}
@@ -0,0 +1,84 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHosts(t *testing.T) {
+	Convey("Given a /30", t, func() {
+		ips, err := hosts("10.0.1.4/30")
+
+		Convey("Then I expect the network and broadcast addresses to be skipped", func() {
+			So(err, ShouldBeNil)
+			So(len(ips), ShouldEqual, 2)
+			So(ips[0].String(), ShouldEqual, "10.0.1.5")
+			So(ips[1].String(), ShouldEqual, "10.0.1.6")
+		})
+	})
+
+	Convey("Given a /32", t, func() {
+		ips, err := hosts("10.0.1.32/32")
+
+		Convey("Then I expect the single host", func() {
+			So(err, ShouldBeNil)
+			So(len(ips), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given a subnet that is too large", t, func() {
+		_, err := hosts("10.0.0.0/8")
+
+		Convey("Then I expect an error", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestSweep(t *testing.T) {
+	Convey("Given a device and a web server that isn't a device", t, func() {
+		fake := &fakeDevice{
+			DeviceType: InsightURN,
+			UDN:        "uuid:Insight-1_0-221326K0101234",
+			Services:   []string{ServiceBasicEvent},
+		}
+		device, stop := fake.start()
+		defer stop()
+
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte("<root><device><deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType></device></root>"))
+		}))
+		defer other.Close()
+
+		saved := devicePorts
+		devicePorts = []int{closedPort(), portOf(other.URL[len("http://"):]), portOf(device.Host)}
+		defer func() { devicePorts = saved }()
+
+		Convey("When I sweep the subnet", func() {
+			deviceInfos, err := (&Wemo{}).Sweep(context.Background(), "127.0.0.1/32")
+
+			Convey("Then I expect only the device", func() {
+				So(err, ShouldBeNil)
+				So(len(deviceInfos), ShouldEqual, 1)
+				So(deviceInfos[0].DeviceType, ShouldEqual, InsightURN)
+				So(deviceInfos[0].Device.Host, ShouldEqual, device.Host)
+			})
+		})
+	})
+}
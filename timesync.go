@@ -0,0 +1,67 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	"code.google.com/p/go.net/context"
+	"strconv"
+	"time"
+)
+
+type timeSyncArgs struct {
+	UTC          int64
+	TimeZone     string
+	Dst          int `xml:"dst"`
+	DstSupported int
+}
+
+// newTimeSyncArgs works out what TimeSync expects from Go's tz database.
+// TimeZone is the standard, non-DST, offset in hours; dst says whether DST
+// is in effect at the moment
+func newTimeSyncArgs(now time.Time, loc *time.Location) timeSyncArgs {
+	now = now.In(loc)
+
+	// DST moves clocks forward, so the smaller of the January and July
+	// offsets is standard time in either hemisphere
+	_, jan := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, loc).Zone()
+	_, jul := time.Date(now.Year(), time.July, 1, 0, 0, 0, 0, loc).Zone()
+	standard := jan
+	if jul < standard {
+		standard = jul
+	}
+	_, current := now.Zone()
+
+	args := timeSyncArgs{
+		UTC:      now.Unix(),
+		TimeZone: strconv.FormatFloat(float64(standard)/3600, 'f', 2, 64),
+	}
+	if jan != jul {
+		args.DstSupported = 1
+	}
+	if current != standard {
+		args.Dst = 1
+	}
+	return args
+}
+
+// SyncTime sets the device clock and time zone.  Devices that can't reach
+// Belkin's servers drift and miss DST changes, which throws off schedules
+func (d *Device) SyncTime(ctx context.Context, loc *time.Location) error {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	_, err := d.Call(ctx, ServiceTimeSync, "TimeSync", newTimeSyncArgs(time.Now(), loc))
+	return err
+}
@@ -0,0 +1,60 @@
+// Copyright 2014 Matt Ho
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package wemo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestTimeSyncArgs(t *testing.T) {
+	Convey("Given New York in the summer", t, func() {
+		loc, _ := time.LoadLocation("America/New_York")
+		now := time.Date(2014, time.July, 4, 12, 0, 0, 0, loc)
+
+		Convey("When I call #newTimeSyncArgs", func() {
+			args := newTimeSyncArgs(now, loc)
+
+			Convey("Then I expect standard time with dst in effect", func() {
+				So(args.UTC, ShouldEqual, now.Unix())
+				So(args.TimeZone, ShouldEqual, "-5.00")
+				So(args.Dst, ShouldEqual, 1)
+				So(args.DstSupported, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given Sydney in July", t, func() {
+		loc, _ := time.LoadLocation("Australia/Sydney")
+		now := time.Date(2014, time.July, 4, 12, 0, 0, 0, loc)
+
+		Convey("Then I expect standard time without dst", func() {
+			args := newTimeSyncArgs(now, loc)
+			So(args.TimeZone, ShouldEqual, "10.00")
+			So(args.Dst, ShouldEqual, 0)
+			So(args.DstSupported, ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given Kolkata", t, func() {
+		loc, _ := time.LoadLocation("Asia/Kolkata")
+
+		Convey("Then I expect a fractional offset and no dst support", func() {
+			args := newTimeSyncArgs(time.Date(2014, time.July, 4, 12, 0, 0, 0, loc), loc)
+			So(args.TimeZone, ShouldEqual, "5.50")
+			So(args.DstSupported, ShouldEqual, 0)
+		})
+	})
+}
@@ -15,6 +15,7 @@ package wemo
 
 import (
 	"code.google.com/p/go.net/context"
+	"fmt"
 	"time"
 )
 
@@ -57,3 +58,28 @@ func (self *Wemo) Toggle(friendlyName string, timeout time.Duration) {
 		device.Toggle()
 	})
 }
+
+// SetBrightness sets every dimmer named friendlyName to percent
+func (self *Wemo) SetBrightness(friendlyName string, percent int, timeout time.Duration) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("brightness must be between 0 and 100 => %d", percent)
+	}
+
+	found := 0
+	var result error
+	err := self.foreach(friendlyName, timeout, func(device *Device) {
+		found++
+		dimmer := &Dimmer{Device: device}
+		if err := dimmer.SetBrightness(context.Background(), percent); err != nil && result == nil {
+			result = err
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if found == 0 {
+		return fmt.Errorf("unable to find device, %s", friendlyName)
+	}
+
+	return result
+}
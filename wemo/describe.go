@@ -0,0 +1,49 @@
+package main
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
+	"log"
+	"strings"
+)
+
+var describeCommand = cli.Command{
+	Name:        "describe",
+	Usage:       "list the actions and state variables a device supports",
+	Description: "fetch the service descriptions (SCPD) advertised by a device",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+	},
+	Action: describeAction,
+}
+
+func describeAction(c *cli.Context) {
+	host := c.String("host")
+	device := &wemo.Device{
+		Host: host,
+	}
+
+	descriptions, err := device.DescribeServices(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, description := range descriptions {
+		fmt.Printf("%s (%s)\n", description.Service.ServiceType, description.Service.ControlURL)
+
+		for _, action := range description.Actions {
+			var args []string
+			for _, arg := range action.Arguments {
+				args = append(args, fmt.Sprintf("%s %s", arg.Direction, arg.Name))
+			}
+			fmt.Printf("  %s(%s)\n", action.Name, strings.Join(args, ", "))
+		}
+
+		for _, variable := range description.StateVariables {
+			fmt.Printf("  %-30s %-12s events=%s\n", variable.Name, variable.DataType, variable.SendEvents)
+		}
+		fmt.Println()
+	}
+}
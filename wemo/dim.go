@@ -0,0 +1,45 @@
+package main
+
+import (
+	"code.google.com/p/go.net/context"
+	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
+	"log"
+	"time"
+)
+
+var dimCommand = cli.Command{
+	Name:        "dim",
+	Usage:       "set the brightness of a dimmer",
+	Description: "select the dimmer by --host or by --name, which searches the local network",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+		cli.StringFlag{"name", "", "friendly name of the dimmer", ""},
+		cli.StringFlag{"interface", "", "search by interface", ""},
+		cli.IntFlag{"level", 100, "brightness in percent; 0 turns the dimmer off", ""},
+		cli.IntFlag{"timeout", 3, "timeout", ""},
+	},
+	Action: dimAction,
+}
+
+func dimAction(c *cli.Context) {
+	level := c.Int("level")
+
+	if host := c.String("host"); host != "" {
+		dimmer := &wemo.Dimmer{
+			Device: &wemo.Device{Host: host},
+		}
+		if err := dimmer.SetBrightness(context.Background(), level); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	api, err := newApi(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := api.SetBrightness(c.String("name"), level, time.Duration(c.Int("timeout"))*time.Second); err != nil {
+		log.Fatal(err)
+	}
+}
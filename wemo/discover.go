@@ -13,27 +13,36 @@ import (
 var discoverCommand = cli.Command{
 	Name:        "discover",
 	Usage:       "find devices in the local network",
-	Description: "search for devices in the local network",
+	Description: "search for devices in the local network; only devices whose setup.xml can be read within --timeout are listed",
 	Flags: []cli.Flag{
 		cli.StringFlag{"interface", "", "search by interface", ""},
-		cli.StringFlag{"ip", "", "discovery wemo by ip", ""},
+		cli.StringFlag{"ip", "", "search from this local ip address", ""},
 		cli.IntFlag{"timeout", 3, "timeout", ""},
+		cli.BoolFlag{"wide", "include model, sku, region and hardware details", ""},
+		cli.StringFlag{"sweep", "", "probe every host in a subnet instead of multicast e.g. 10.0.1.0/24", ""},
+		cli.IntFlag{"sweep-timeout", 60, "seconds before a sweep stops and reports what it found", ""},
 	},
 	Action: commandAction,
 }
 
 func commandAction(c *cli.Context) {
-	timeout := c.Int("timeout")
-	iface := c.String("interface")
+	timeout := time.Duration(c.Int("timeout")) * time.Second
 
-	api, err := wemo.NewByInterface(iface)
+	var deviceInfos wemo.DeviceInfos
+	var err error
+	if cidr := c.String("sweep"); cidr != "" {
+		deviceInfos, err = sweep(cidr, time.Duration(c.Int("sweep-timeout"))*time.Second)
+	} else {
+		deviceInfos, err = discover(c, timeout)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+	sort.Sort(deviceInfos)
 
-	devices, err := api.DiscoverAll(time.Duration(timeout) * time.Second)
-	if err != nil {
-		log.Fatal(err)
+	if c.Bool("wide") {
+		printWide(deviceInfos)
+		return
 	}
 
 	format := "%-20s %-20s %-21s %-20s\n"
@@ -50,21 +59,89 @@ func commandAction(c *cli.Context) {
 		"----------------",
 	)
 
-	deviceInfos := wemo.DeviceInfos{}
-	for _, device := range devices {
-		deviceInfo, err := device.FetchDeviceInfo(context.Background())
-		if err != nil {
-			log.Fatal(err)
-		}
-		deviceInfos = append(deviceInfos, deviceInfo)
+	for _, deviceInfo := range deviceInfos {
+		fmt.Printf(format,
+			deviceInfo.Device.CurrentHost(),
+			deviceInfo.FriendlyName,
+			deviceInfo.FirmwareVersion,
+			deviceInfo.SerialNumber)
 	}
+}
+
+func discover(c *cli.Context, timeout time.Duration) (wemo.DeviceInfos, error) {
+	api, err := newApi(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.DiscoverDeviceInfos(timeout)
+}
+
+// sweep reports the devices found so far when the deadline passes
+func sweep(cidr string, timeout time.Duration) (wemo.DeviceInfos, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	deviceInfos, err := (&wemo.Wemo{}).Sweep(ctx, cidr)
+	if err == context.DeadlineExceeded {
+		log.Printf("sweep of %s stopped after %v; results are incomplete\n", cidr, timeout)
+		return deviceInfos, nil
+	}
+	return deviceInfos, err
+}
+
+func printWide(deviceInfos wemo.DeviceInfos) {
+	format := "%-20s %-20s %-14s %-21s %-20s %-12s %-10s %-6s %-8s %-6s\n"
+	fmt.Printf(format,
+		"Host",
+		"Friendly Name",
+		"Type",
+		"Firmware Version",
+		"Serial Number",
+		"Product",
+		"SKU",
+		"Region",
+		"Hardware",
+		"State",
+	)
+	fmt.Printf(format,
+		"----------------",
+		"----------------",
+		"------------",
+		"----------------",
+		"----------------",
+		"----------",
+		"--------",
+		"------",
+		"--------",
+		"-----",
+	)
 
-	sort.Sort(deviceInfos)
 	for _, deviceInfo := range deviceInfos {
+		details, err := deviceInfo.Device.FetchDeviceDetails(context.Background())
+		if err != nil {
+			fmt.Printf("%-20s %s\n", deviceInfo.Device.CurrentHost(), err)
+			continue
+		}
+
+		state := "-"
+		if details.BinaryState >= 0 {
+			state = fmt.Sprintf("%d", details.BinaryState)
+		}
+		if details.Brightness >= 0 {
+			state = fmt.Sprintf("%s/%d%%", state, details.Brightness)
+		}
+
 		fmt.Printf(format,
-			deviceInfo.Device.Host,
+			deviceInfo.Device.CurrentHost(),
 			deviceInfo.FriendlyName,
+			deviceInfo.Type().Name,
 			deviceInfo.FirmwareVersion,
-			deviceInfo.SerialNumber)
+			deviceInfo.SerialNumber,
+			details.ProductName,
+			details.SKU,
+			details.Region,
+			details.HardwareRevision,
+			state)
 	}
 }
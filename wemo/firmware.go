@@ -0,0 +1,105 @@
+package main
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
+	"log"
+	"time"
+)
+
+var firmwareCommand = cli.Command{
+	Name:  "firmware",
+	Usage: "inspect and update device firmware",
+	Subcommands: []cli.Command{
+		firmwareStatusCommand,
+		firmwarePushCommand,
+	},
+}
+
+var firmwareStatusCommand = cli.Command{
+	Name:        "status",
+	Usage:       "print the firmware version of devices",
+	Description: "query a single device with --host or every device found on --interface with --all",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+		cli.BoolFlag{"all", "query every device in the local network", ""},
+		cli.StringFlag{"interface", "", "search by interface", ""},
+		cli.IntFlag{"timeout", 3, "timeout", ""},
+	},
+	Action: firmwareStatusAction,
+}
+
+func firmwareStatusAction(c *cli.Context) {
+	var devices []*wemo.Device
+	if c.Bool("all") {
+		api, err := newApi(c)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		devices, err = api.DiscoverAll(time.Duration(c.Int("timeout")) * time.Second)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		devices = append(devices, &wemo.Device{Host: c.String("host")})
+	}
+
+	for _, device := range devices {
+		info, err := device.FirmwareVersion(context.Background())
+		if err != nil {
+			fmt.Printf("%-20s %s\n", device.CurrentHost(), err)
+			continue
+		}
+		fmt.Printf("%-20s %-40s %s\n", device.CurrentHost(), info.Version, info.SkuNo)
+	}
+}
+
+var firmwarePushCommand = cli.Command{
+	Name:        "push",
+	Usage:       "update a device from a local firmware image",
+	Description: "serves --file on --listen and waits for the device to download it",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+		cli.StringFlag{"file", "", "firmware image to push", ""},
+		cli.StringFlag{"listen", "", "local ip the device can reach e.g. 10.0.1.5", ""},
+		cli.StringFlag{"version", "", "version of the new firmware", ""},
+		cli.StringFlag{"signature", "", "image signature; omit for unsigned images", ""},
+		cli.IntFlag{"timeout", 300, "seconds to wait for the download", ""},
+	},
+	Action: firmwarePushAction,
+}
+
+func firmwarePushAction(c *cli.Context) {
+	if c.String("host") == "" || c.String("file") == "" || c.String("listen") == "" {
+		log.Fatal("--host, --file and --listen are required")
+	}
+
+	server, err := wemo.ServeFirmware(c.String("listen"), c.String("file"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer server.Close()
+
+	device := &wemo.Device{Host: c.String("host")}
+	err = device.UpdateFirmware(context.Background(), wemo.FirmwareUpdate{
+		Version:     c.String("version"),
+		ReleaseDate: time.Now().Format("01022006"),
+		URL:         server.URL(),
+		Signature:   c.String("signature"),
+		Unsigned:    c.String("signature") == "",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("waiting for %s to download %s\n", device.CurrentHost(), server.URL())
+
+	select {
+	case <-server.Downloaded():
+		fmt.Println("downloaded; the device will flash and reboot")
+	case <-time.After(time.Duration(c.Int("timeout")) * time.Second):
+		log.Fatal("timed out waiting for the device to download the image")
+	}
+}
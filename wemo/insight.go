@@ -0,0 +1,88 @@
+package main
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
+	"log"
+)
+
+var insightCommand = cli.Command{
+	Name:  "insight",
+	Usage: "read and configure insight power metering",
+	Subcommands: []cli.Command{
+		insightParamsCommand,
+		insightThresholdCommand,
+	},
+}
+
+var insightParamsCommand = cli.Command{
+	Name:  "params",
+	Usage: "print the current power readings",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+	},
+	Action: insightParamsAction,
+}
+
+func insightParamsAction(c *cli.Context) {
+	insight := &wemo.Insight{
+		Device: &wemo.Device{Host: c.String("host")},
+	}
+
+	params, err := insight.InsightParams(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	format := "%-16s %v\n"
+	fmt.Printf(format, "State", params.State)
+	fmt.Printf(format, "Last Change", params.LastChange)
+	fmt.Printf(format, "On For", params.OnFor)
+	fmt.Printf(format, "On Today", params.OnToday)
+	fmt.Printf(format, "On Total", params.OnTotal)
+	fmt.Printf(format, "Current Power", fmt.Sprintf("%.3f W", params.CurrentPower))
+	fmt.Printf(format, "Today Energy", fmt.Sprintf("%.3f Wh", params.TodayEnergy))
+	fmt.Printf(format, "Total Energy", fmt.Sprintf("%.3f Wh", params.TotalEnergy))
+	fmt.Printf(format, "Threshold", fmt.Sprintf("%.3f W", params.Threshold))
+}
+
+var insightThresholdCommand = cli.Command{
+	Name:        "threshold",
+	Usage:       "show or change the standby power threshold",
+	Description: "without options, prints the current threshold in watts",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+		cli.Float64Flag{"set", -1, "new threshold in watts", ""},
+		cli.BoolFlag{"auto", "learn the threshold from the attached appliance", ""},
+		cli.BoolFlag{"reset", "restore the default threshold", ""},
+	},
+	Action: insightThresholdAction,
+}
+
+func insightThresholdAction(c *cli.Context) {
+	ctx := context.Background()
+	insight := &wemo.Insight{
+		Device: &wemo.Device{Host: c.String("host")},
+	}
+
+	var err error
+	switch {
+	case c.Bool("reset"):
+		err = insight.ResetPowerThreshold(ctx)
+	case c.Bool("auto"):
+		err = insight.SetAutoPowerThreshold(ctx)
+	case c.Float64("set") >= 0:
+		err = insight.SetPowerThreshold(ctx, c.Float64("set"))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	threshold, err := insight.PowerThreshold(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%.3f W\n", threshold)
+}
@@ -15,6 +15,7 @@ package main
 
 import (
 	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
 	"os"
 )
 
@@ -25,9 +26,32 @@ func main() {
 	app.Version = "0.1"
 	app.Commands = []cli.Command{
 		discoverCommand,
+		describeCommand,
 		onCommand,
 		offCommand,
 		toggleCommand,
+		dimCommand,
+		watchMotionCommand,
+		scheduleCommand,
+		timesyncCommand,
+		provisionCommand,
+		insightCommand,
+		firmwareCommand,
+		renameCommand,
+		signalCommand,
+		resetCommand,
 	}
 	app.Run(os.Args)
 }
+
+// newApi searches from --ip or --interface when given and from every usable
+// interface otherwise
+func newApi(c *cli.Context) (*wemo.Wemo, error) {
+	if ip := c.String("ip"); ip != "" {
+		return wemo.NewByIp(ip), nil
+	}
+	if iface := c.String("interface"); iface != "" {
+		return wemo.NewByInterface(iface)
+	}
+	return wemo.NewAuto(), nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
+	"log"
+)
+
+var renameCommand = cli.Command{
+	Name:  "rename",
+	Usage: "change the friendly name of a device",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+		cli.StringFlag{"name", "", "new friendly name", ""},
+	},
+	Action: renameAction,
+}
+
+func renameAction(c *cli.Context) {
+	if c.String("name") == "" {
+		log.Fatal("--name is required")
+	}
+
+	device := &wemo.Device{Host: c.String("host")}
+	if err := device.ChangeFriendlyName(context.Background(), c.String("name")); err != nil {
+		log.Fatal(err)
+	}
+}
+
+var signalCommand = cli.Command{
+	Name:  "signal",
+	Usage: "print the wifi signal strength of a device",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+	},
+	Action: signalAction,
+}
+
+func signalAction(c *cli.Context) {
+	device := &wemo.Device{Host: c.String("host")}
+	strength, err := device.GetSignalStrength(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%-20s %d%%\n", device.CurrentHost(), strength)
+}
+
+var resetCommand = cli.Command{
+	Name:        "reset",
+	Usage:       "clear personal data, forget wifi or restore factory defaults",
+	Description: "resets are destructive and require --yes",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+		cli.StringFlag{"mode", "data", "data, wifi or all", ""},
+		cli.BoolFlag{"yes", "confirm the reset", ""},
+	},
+	Action: resetAction,
+}
+
+func resetAction(c *cli.Context) {
+	var mode wemo.ResetMode
+	switch c.String("mode") {
+	case "data":
+		mode = wemo.ResetData
+	case "wifi":
+		mode = wemo.ResetWiFi
+	case "all":
+		mode = wemo.ResetAll
+	default:
+		log.Fatalf("unknown reset mode, %s", c.String("mode"))
+	}
+
+	device := &wemo.Device{Host: c.String("host")}
+	if !c.Bool("yes") {
+		log.Fatalf("refusing to reset %s without --yes", device.CurrentHost())
+	}
+
+	if err := device.ReSetup(context.Background(), mode); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%-20s reset (%s)\n", device.CurrentHost(), c.String("mode"))
+}
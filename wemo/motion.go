@@ -0,0 +1,40 @@
+package main
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
+	"log"
+	"time"
+)
+
+var watchMotionCommand = cli.Command{
+	Name:        "watch-motion",
+	Usage:       "print motion events from a motion sensor",
+	Description: "with --callback, events are pushed by the sensor; otherwise the sensor is polled",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+		cli.StringFlag{"callback", "", "local ip address the sensor can send events to", ""},
+	},
+	Action: watchMotionAction,
+}
+
+func watchMotionAction(c *cli.Context) {
+	sensor := &wemo.MotionSensor{
+		Device: &wemo.Device{Host: c.String("host")},
+	}
+
+	events, err := sensor.Watch(context.Background(), c.String("callback"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for event := range events {
+		state := "no motion"
+		if event.Motion {
+			state = "motion"
+		}
+		fmt.Printf("%s %s\n", event.Time.Format(time.RFC3339), state)
+	}
+}
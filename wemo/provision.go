@@ -0,0 +1,52 @@
+package main
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
+	"log"
+	"time"
+)
+
+var provisionCommand = cli.Command{
+	Name:        "provision",
+	Usage:       "join a factory reset device to a wifi network",
+	Description: "connect to the device's own access point first; without --ssid, lists the networks the device can see",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", wemo.DefaultSetupHost, "device host and ip", ""},
+		cli.StringFlag{"ssid", "", "network to join", ""},
+		cli.StringFlag{"passphrase", "", "network passphrase", ""},
+		cli.IntFlag{"timeout", 60, "seconds to wait for the device to join", ""},
+	},
+	Action: provisionAction,
+}
+
+func provisionAction(c *cli.Context) {
+	provisioner := &wemo.Provisioner{
+		Device: &wemo.Device{Host: c.String("host")},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Int("timeout"))*time.Second)
+	defer cancel()
+
+	ssid := c.String("ssid")
+	if ssid == "" {
+		accessPoints, err := provisioner.AccessPoints(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		format := "%-32s %-8s %-8s %s\n"
+		fmt.Printf(format, "SSID", "Channel", "Signal", "Security")
+		for _, ap := range accessPoints {
+			fmt.Printf(format, ap.SSID, fmt.Sprint(ap.Channel), fmt.Sprint(ap.Signal), ap.Auth+"/"+ap.Encrypt)
+		}
+		return
+	}
+
+	if err := provisioner.Connect(ctx, ssid, c.String("passphrase")); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s joined %s\n", c.String("host"), ssid)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
+	"github.com/savaki/go.wemo/rules"
+	"log"
+	"strconv"
+	"time"
+)
+
+var scheduleCommand = cli.Command{
+	Name:  "schedule",
+	Usage: "manage the schedules stored on a device",
+	Subcommands: []cli.Command{
+		{
+			Name:  "list",
+			Usage: "list the schedules on a device",
+			Flags: []cli.Flag{
+				cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+			},
+			Action: scheduleListAction,
+		},
+		{
+			Name:        "add",
+			Usage:       "add a schedule to a device",
+			Description: "e.g. --days weekdays --at sunset-15m --action on --lat 37.77 --long -122.42",
+			Flags: []cli.Flag{
+				cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+				cli.StringFlag{"name", "", "name of the schedule", ""},
+				cli.StringFlag{"days", "daily", "daily, weekdays, weekends or e.g. mon,wed,fri", ""},
+				cli.StringFlag{"at", "", "HH:MM, sunrise or sunset with an optional offset e.g. sunset-15m", ""},
+				cli.StringFlag{"action", "on", "on, off or toggle", ""},
+				cli.StringFlag{"countdown", "", "perform action this long after the device is turned on e.g. 30m", ""},
+				cli.StringFlag{"away-until", "", "randomly switch the device from --at until this time", ""},
+				cli.Float64Flag{"lat", 0, "latitude of the device, for sunrise and sunset", ""},
+				cli.Float64Flag{"long", 0, "longitude of the device, for sunrise and sunset", ""},
+			},
+			Action: scheduleAddAction,
+		},
+		{
+			Name:  "rm",
+			Usage: "remove a schedule from a device",
+			Flags: []cli.Flag{
+				cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+				cli.IntFlag{"id", 0, "id of the schedule, as shown by list (required)", ""},
+			},
+			Action: scheduleRemoveAction,
+		},
+	},
+}
+
+func scheduler(c *cli.Context) *rules.Scheduler {
+	return rules.Schedules(&wemo.Device{Host: c.String("host")})
+}
+
+func scheduleListAction(c *cli.Context) {
+	scheduled, err := scheduler(c).List(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	format := "%-6s %-30s %-16s %-6s %-8s %s\n"
+	fmt.Printf(format, "ID", "Name", "Type", "State", "Day", "Start")
+	fmt.Printf(format, "----", "----------------", "----------------", "-----", "------", "--------")
+	for _, rule := range scheduled {
+		for _, device := range rule.Devices {
+			start := time.Duration(device.StartTime) * time.Second
+			fmt.Printf(format,
+				strconv.Itoa(rule.RuleID),
+				rule.Name,
+				rule.Type,
+				rule.State,
+				strconv.Itoa(device.DayID),
+				fmt.Sprintf("%02d:%02d", int(start/time.Hour), int(start%time.Hour/time.Minute)))
+		}
+	}
+}
+
+func scheduleAddAction(c *cli.Context) {
+	action, err := rules.ParseAction(c.String("action"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	schedule := rules.Schedule{
+		Name:   c.String("name"),
+		Action: action,
+	}
+
+	switch {
+	case c.String("countdown") != "":
+		schedule.Type = rules.Countdown
+		if schedule.Delay, err = time.ParseDuration(c.String("countdown")); err != nil {
+			log.Fatal(err)
+		}
+
+	default:
+		if schedule.Days, err = rules.ParseDays(c.String("days")); err != nil {
+			log.Fatal(err)
+		}
+		if schedule.At, err = rules.ParseAt(c.String("at")); err != nil {
+			log.Fatal(err)
+		}
+		if until := c.String("away-until"); until != "" {
+			schedule.Type = rules.Away
+			if schedule.Until, err = rules.ParseAt(until); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	s := scheduler(c)
+	s.Latitude = c.Float64("lat")
+	s.Longitude = c.Float64("long")
+
+	ruleID, err := s.Add(context.Background(), schedule)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("added schedule %d\n", ruleID)
+}
+
+func scheduleRemoveAction(c *cli.Context) {
+	ruleID := c.Int("id")
+	if ruleID <= 0 {
+		log.Fatal("--id is required")
+	}
+
+	if err := scheduler(c).Remove(context.Background(), ruleID); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"code.google.com/p/go.net/context"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/savaki/go.wemo"
+	"log"
+	"time"
+)
+
+var timesyncCommand = cli.Command{
+	Name:        "timesync",
+	Usage:       "push the current time and time zone to devices",
+	Description: "sync a single device with --host or every device found on --interface with --all",
+	Flags: []cli.Flag{
+		cli.StringFlag{"host", "", "device host and ip e.g. 10.0.1.2:49128", ""},
+		cli.BoolFlag{"all", "sync every device in the local network", ""},
+		cli.StringFlag{"interface", "", "search by interface", ""},
+		cli.StringFlag{"tz", "", "time zone e.g. America/Los_Angeles; defaults to local", ""},
+		cli.IntFlag{"timeout", 3, "timeout", ""},
+	},
+	Action: timesyncAction,
+}
+
+func timesyncAction(c *cli.Context) {
+	loc := time.Local
+	if tz := c.String("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var devices []*wemo.Device
+	if c.Bool("all") {
+		api, err := newApi(c)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		devices, err = api.DiscoverAll(time.Duration(c.Int("timeout")) * time.Second)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		devices = append(devices, &wemo.Device{Host: c.String("host")})
+	}
+
+	for _, device := range devices {
+		err := device.SyncTime(context.Background(), loc)
+		if err != nil {
+			fmt.Printf("%-20s %s\n", device.CurrentHost(), err)
+			continue
+		}
+		fmt.Printf("%-20s synced\n", device.CurrentHost())
+	}
+}